@@ -58,6 +58,13 @@ async function register() {
 }
 
 function logout() {
+    if (state.token) {
+        // Revoke the session server-side; ignore failures since we clear local state anyway
+        fetch(`${API_URL}/auth/logout`, {
+            method: 'POST',
+            headers: { 'Authorization': `Bearer ${state.token}` }
+        }).catch(() => {});
+    }
     if (state.ws) state.ws.close();
     state = { token: null, user: null, ws: null, activeRoom: null };
     document.getElementById('auth-section').classList.remove('hidden');
@@ -133,8 +140,24 @@ function joinRoom(roomId) {
     console.log(`Sent join_room for room ${roomId}`);
 }
 
-function connectWebSocket(onConnected) {
-    state.ws = new WebSocket(`${WS_URL}?token=${state.token}`);
+async function connectWebSocket(onConnected) {
+    // Exchange the access token for a short-lived, single-use ticket so the
+    // JWT never appears in the websocket URL
+    let ticket;
+    try {
+        const res = await fetch(`${API_URL}/ws/ticket`, {
+            method: 'POST',
+            headers: { 'Authorization': `Bearer ${state.token}` }
+        });
+        const data = await res.json();
+        if (!res.ok) throw new Error(data.error);
+        ticket = data.ticket;
+    } catch (err) {
+        addSystemMessage(`Could not connect: ${err.message}`);
+        return;
+    }
+
+    state.ws = new WebSocket(`${WS_URL}?ticket=${encodeURIComponent(ticket)}`);
 
     state.ws.onopen = () => {
         console.log('Connected to WebSocket');
@@ -0,0 +1,284 @@
+// Command fake-idp is a minimal OpenID Connect provider for local development.
+// It signs in anyone with whatever email they type, so never expose it publicly.
+//
+//	go run ./cmd/fake-idp -addr :9000 -issuer http://localhost:9000 -client-id meetup-local
+//
+// Then configure the backend with:
+//
+//	OIDC_PROVIDERS=fake
+//	OIDC_FAKE_ISSUER=http://localhost:9000
+//	OIDC_FAKE_CLIENT_ID=meetup-local
+//
+// Passing login_hint=<email> to /authorize skips the sign-in form, which makes
+// the flow scriptable with curl.
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"html/template"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const keyID = "fake-idp-1"
+
+// authorization is an issued, not yet redeemed authorization code
+type authorization struct {
+	ClientID      string
+	RedirectURI   string
+	CodeChallenge string
+	Nonce         string
+	Email         string
+	Name          string
+	EmailVerified bool
+	ExpiresAt     time.Time
+}
+
+type server struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	key          *rsa.PrivateKey
+
+	mutex sync.Mutex
+	codes map[string]authorization
+}
+
+var loginForm = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html><body style="font-family: sans-serif; max-width: 360px; margin: 60px auto">
+<h2>Fake Identity Provider</h2>
+<form method="POST" action="/authorize">
+  {{range $k, $v := .Params}}<input type="hidden" name="{{$k}}" value="{{index $v 0}}">{{end}}
+  <p><label>Email<br><input name="login_hint" type="email" required style="width: 100%"></label></p>
+  <p><label>Name<br><input name="name" style="width: 100%"></label></p>
+  <p><label><input name="email_verified" type="checkbox" value="true" checked> Email verified</label></p>
+  <button type="submit">Sign in</button>
+</form>
+</body></html>`))
+
+func main() {
+	addr := flag.String("addr", ":9000", "Listen address")
+	issuer := flag.String("issuer", "http://localhost:9000", "Issuer URL (must match OIDC_<NAME>_ISSUER)")
+	clientID := flag.String("client-id", "meetup-local", "Accepted client ID")
+	clientSecret := flag.String("client-secret", "", "Required client secret (empty = public client)")
+	flag.Parse()
+
+	s, err := newServer(*issuer, *clientID, *clientSecret)
+	if err != nil {
+		log.Fatal("Failed to generate signing key:", err)
+	}
+
+	log.Printf("Fake identity provider listening on %s (issuer %s, client %s)", *addr, s.issuer, s.clientID)
+	log.Fatal(http.ListenAndServe(*addr, s.routes()))
+}
+
+// newServer creates a provider with a fresh signing key
+func newServer(issuer, clientID, clientSecret string) (*server, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	return &server{
+		issuer:       strings.TrimSuffix(issuer, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		key:          key,
+		codes:        make(map[string]authorization),
+	}, nil
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/jwks", s.jwks)
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+	return mux
+}
+
+func (s *server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.issuer,
+		"authorization_endpoint":                s.issuer + "/authorize",
+		"token_endpoint":                        s.issuer + "/token",
+		"jwks_uri":                              s.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (s *server) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := s.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize shows a sign-in form, or issues a code right away when login_hint is given
+func (s *server) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	params := r.Form
+
+	if params.Get("client_id") != s.clientID {
+		http.Error(w, "unknown client_id", http.StatusBadRequest)
+		return
+	}
+	redirectURI, err := url.Parse(params.Get("redirect_uri"))
+	if err != nil || redirectURI.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if params.Get("response_type") != "code" || params.Get("code_challenge_method") != "S256" || params.Get("code_challenge") == "" {
+		redirectWithError(w, r, redirectURI, params.Get("state"), "invalid_request")
+		return
+	}
+
+	email := params.Get("login_hint")
+	if email == "" {
+		loginForm.Execute(w, map[string]interface{}{"Params": params})
+		return
+	}
+
+	// From the form, an unchecked box means unverified; from a URL, verified by default
+	emailVerified := params.Get("email_verified") == "true" || (r.Method == http.MethodGet && params.Get("email_verified") == "")
+
+	code := randomString()
+	s.mutex.Lock()
+	s.codes[code] = authorization{
+		ClientID:      params.Get("client_id"),
+		RedirectURI:   params.Get("redirect_uri"),
+		CodeChallenge: params.Get("code_challenge"),
+		Nonce:         params.Get("nonce"),
+		Email:         email,
+		Name:          params.Get("name"),
+		EmailVerified: emailVerified,
+		ExpiresAt:     time.Now().Add(time.Minute),
+	}
+	s.mutex.Unlock()
+
+	query := redirectURI.Query()
+	query.Set("code", code)
+	query.Set("state", params.Get("state"))
+	redirectURI.RawQuery = query.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+// token redeems an authorization code (with PKCE) for an ID token
+func (s *server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != s.clientID || (s.clientSecret != "" && clientSecret != s.clientSecret) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	s.mutex.Lock()
+	auth, found := s.codes[code]
+	delete(s.codes, code) // Codes are single-use
+	s.mutex.Unlock()
+
+	if !found || time.Now().After(auth.ExpiresAt) || auth.ClientID != clientID || auth.RedirectURI != r.PostForm.Get("redirect_uri") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != auth.CodeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	// The subject is stable per email so repeated logins map to the same identity
+	subject := sha256.Sum256([]byte(strings.ToLower(auth.Email)))
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            s.issuer,
+		"aud":            clientID,
+		"sub":            hex.EncodeToString(subject[:8]),
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"email":          auth.Email,
+		"email_verified": auth.EmailVerified,
+	}
+	if auth.Nonce != "" {
+		claims["nonce"] = auth.Nonce
+	}
+	if auth.Name != "" {
+		claims["name"] = auth.Name
+	}
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = keyID
+	signed, err := idToken.SignedString(s.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signed,
+	})
+}
+
+func redirectWithError(w http.ResponseWriter, r *http.Request, redirectURI *url.URL, state, code string) {
+	query := redirectURI.Query()
+	query.Set("error", code)
+	query.Set("state", state)
+	redirectURI.RawQuery = query.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	bytes := make([]byte, 24)
+	rand.Read(bytes)
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
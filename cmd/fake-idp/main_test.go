@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"meetup_backend/internal/oidc"
+)
+
+const (
+	testClientID    = "meetup-test"
+	testRedirectURL = "http://localhost:8080/api/auth/oidc/fake/callback"
+)
+
+// startIdP runs the fake provider and returns a backend provider configured for it
+func startIdP(t *testing.T) *oidc.Provider {
+	t.Helper()
+
+	s, err := newServer("", testClientID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s.routes())
+	t.Cleanup(ts.Close)
+	s.issuer = ts.URL
+
+	return oidc.NewProvider(oidc.Config{
+		Name:        "fake",
+		Issuer:      ts.URL,
+		ClientID:    testClientID,
+		RedirectURL: testRedirectURL,
+	})
+}
+
+// signIn opens the authorization URL as the given user and returns the callback parameters
+func signIn(t *testing.T, authURL, email string) url.Values {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	query.Set("login_hint", email)
+	u.RawQuery = query.Encode()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize returned %d, want %d", resp.StatusCode, http.StatusFound)
+	}
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := callback.Scheme + "://" + callback.Host + callback.Path; got != testRedirectURL {
+		t.Fatalf("redirected to %s, want %s", got, testRedirectURL)
+	}
+	return callback.Query()
+}
+
+// startLogin builds the authorization URL the way the backend does and returns it with the PKCE verifier
+func startLogin(t *testing.T, provider *oidc.Provider, state, nonce string) (string, string) {
+	t.Helper()
+
+	verifier, err := oidc.GenerateCodeVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := provider.AuthCodeURL(context.Background(), state, nonce, oidc.CodeChallengeS256(verifier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return authURL, verifier
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	provider := startIdP(t)
+	ctx := context.Background()
+
+	authURL, verifier := startLogin(t, provider, "state-123", "nonce-456")
+	callback := signIn(t, authURL, "alice@example.com")
+
+	if got := callback.Get("state"); got != "state-123" {
+		t.Fatalf("state = %q, want %q", got, "state-123")
+	}
+	code := callback.Get("code")
+	if code == "" {
+		t.Fatal("no code in callback")
+	}
+
+	rawIDToken, err := provider.Exchange(ctx, code, verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	claims, err := provider.VerifyIDToken(ctx, rawIDToken, "nonce-456")
+	if err != nil {
+		t.Fatalf("VerifyIDToken: %v", err)
+	}
+	if claims.Email != "alice@example.com" || !claims.EmailVerified || claims.Subject == "" {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+
+	// Codes are single-use
+	if _, err := provider.Exchange(ctx, code, verifier); err == nil {
+		t.Fatal("Exchange accepted a code that was already redeemed")
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	provider := startIdP(t)
+
+	authURL, _ := startLogin(t, provider, "state", "nonce")
+	callback := signIn(t, authURL, "bob@example.com")
+
+	otherVerifier, err := oidc.GenerateCodeVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Exchange(context.Background(), callback.Get("code"), otherVerifier); err == nil {
+		t.Fatal("Exchange accepted a code with the wrong PKCE verifier")
+	}
+}
+
+func TestVerifyIDTokenRejectsWrongNonce(t *testing.T) {
+	provider := startIdP(t)
+	ctx := context.Background()
+
+	authURL, verifier := startLogin(t, provider, "state", "nonce-for-this-login")
+	callback := signIn(t, authURL, "carol@example.com")
+
+	rawIDToken, err := provider.Exchange(ctx, callback.Get("code"), verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if _, err := provider.VerifyIDToken(ctx, rawIDToken, "nonce-from-another-login"); !errors.Is(err, oidc.ErrInvalidIDToken) {
+		t.Fatalf("VerifyIDToken error = %v, want %v", err, oidc.ErrInvalidIDToken)
+	}
+}
+
+func TestVerifyIDTokenRejectsOtherIssuer(t *testing.T) {
+	provider := startIdP(t)
+	other := startIdP(t)
+	ctx := context.Background()
+
+	// A token from another provider must not verify, even for the same client
+	authURL, verifier := startLogin(t, other, "state", "nonce")
+	callback := signIn(t, authURL, "dave@example.com")
+
+	rawIDToken, err := other.Exchange(ctx, callback.Get("code"), verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if _, err := provider.VerifyIDToken(ctx, rawIDToken, "nonce"); err == nil {
+		t.Fatal("VerifyIDToken accepted a token from another issuer")
+	}
+}
@@ -1,7 +1,11 @@
 package config
 
 import (
+	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -11,12 +15,85 @@ type Config struct {
 	AppPort     string
 	HOST        string
 	DatabaseURL string
+	AppURL      string // Public base URL used in links sent by email
 
 	Debug bool
 
 	// JWT Settings
-	JWTSecret     string
-	JWTExpiration string
+	JWTAlgorithm           string        // RS256 or EdDSA
+	JWTIssuer              string        // iss claim
+	JWTAudience            string        // aud claim
+	JWTKeyRotationInterval time.Duration // How long a signing key is used before rotation
+	JWTKeyGracePeriod      time.Duration // How long a rotated key still verifies tokens
+	JWTExpiration          time.Duration // Access token lifetime
+	RefreshTokenExpiration time.Duration // Refresh token / session lifetime
+
+	// Mail Settings
+	MailDriver   string // "smtp" or "fake"
+	MailFakeDir  string // Where the fake mailer writes emails (optional)
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	// Email Verification
+	EmailVerificationExpiration time.Duration
+	EmailVerificationResendWait time.Duration
+	RequireVerifiedEmail        bool // Block unverified users from selling and confirming meetups
+
+	// Password Reset
+	PasswordResetExpiration time.Duration
+
+	// Login Protection
+	BcryptCost           int
+	LoginWindow          time.Duration // Sliding window for counting failed attempts
+	LoginFreeAttempts    int           // Failures per account before backoff starts
+	LoginMaxAttempts     int           // Failures per account before lockout
+	LoginIPFreeAttempts  int
+	LoginIPMaxAttempts   int
+	LoginBackoffBase     time.Duration
+	LoginBackoffMax      time.Duration
+	LoginLockoutDuration time.Duration
+
+	// Two-Factor Authentication
+	TOTPIssuer string // Shown as the account name prefix in authenticator apps
+
+	// SMS Settings
+	SMSDriver        string // "twilio" or "fake" (codes are not delivered)
+	TwilioAccountSID string
+	TwilioAuthToken  string
+	TwilioFrom       string // Sender number or messaging service SID
+
+	// Phone Verification
+	PhoneOTPExpiration  time.Duration
+	PhoneOTPMaxAttempts int           // Wrong codes before a new code must be requested
+	PhoneOTPResendWait  time.Duration // Minimum time between two codes
+
+	// Reports
+	ReportHideThreshold int           // Open reports from different users before a product is hidden automatically
+	MessageReportWindow time.Duration // How long read messages are kept out of sight so they can still be reported
+
+	// Reviews
+	ReviewWindow time.Duration // How long after a meetup its participants can review each other
+
+	// Meetup Outcomes
+	MeetupOutcomeWindow time.Duration // How long after a meetup its participants can report whether it happened
+
+	// Point Top-Up
+	PaymentProvider        string        // "fake" (development only); empty disables top-ups
+	PaymentWebhookSecret   string        // Verifies provider webhooks, required when a provider is set
+	PaymentOrderExpiration time.Duration // How long a checkout can be paid
+
+	// Geocoding
+	GazetteerPath string // GeoNames-format file with administrative areas, loaded at startup
+
+	// Account Deletion
+	AccountDeletionGracePeriod time.Duration // Time to change your mind before the account is anonymised
+
+	// Social Login (OpenID Connect)
+	OIDCProviders       []OIDCProvider
+	OIDCStateExpiration time.Duration // How long the user has to finish signing in at the provider
 
 	// CORS Settings
 	CORSAllowOrigins []string
@@ -24,6 +101,17 @@ type Config struct {
 	CORSAllowHeaders []string
 }
 
+// OIDCProvider is an external OpenID Connect identity provider, configured with
+// OIDC_PROVIDERS=google and OIDC_GOOGLE_ISSUER, OIDC_GOOGLE_CLIENT_ID, ...
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
 func LoadConfig() *Config {
 	// Implementation to load configuration from environment variables or config files
 	err := godotenv.Load()
@@ -35,15 +123,148 @@ func LoadConfig() *Config {
 		AppPort:     os.Getenv("PORT"),
 		DatabaseURL: os.Getenv("DATABASE_URL"),
 		HOST:        os.Getenv("HOST"),
+		AppURL:      getString("APP_URL", "http://localhost:"+os.Getenv("PORT")),
 		Debug:       true,
 
-		JWTSecret:     os.Getenv("JWT_SECRET"),
-		JWTExpiration: os.Getenv("JWT_EXPIRES_IN"),
+		JWTAlgorithm:           getString("JWT_ALGORITHM", "EdDSA"),
+		JWTIssuer:              getString("JWT_ISSUER", "meetup-backend"),
+		JWTAudience:            getString("JWT_AUDIENCE", "meetup-api"),
+		JWTKeyRotationInterval: getDuration("JWT_KEY_ROTATION_INTERVAL", 30*24*time.Hour),
+		JWTKeyGracePeriod:      getDuration("JWT_KEY_GRACE_PERIOD", 48*time.Hour),
+		JWTExpiration:          getDuration("JWT_EXPIRES_IN", 15*time.Minute),
+		RefreshTokenExpiration: getDuration("REFRESH_TOKEN_EXPIRES_IN", 30*24*time.Hour),
+
+		MailDriver:   getString("MAIL_DRIVER", "fake"),
+		MailFakeDir:  os.Getenv("MAIL_FAKE_DIR"),
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     getString("SMTP_PORT", "587"),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:     getString("SMTP_FROM", "no-reply@meetup.local"),
+
+		EmailVerificationExpiration: getDuration("EMAIL_VERIFICATION_EXPIRES_IN", 24*time.Hour),
+		EmailVerificationResendWait: getDuration("EMAIL_VERIFICATION_RESEND_WAIT", time.Minute),
+		RequireVerifiedEmail:        getBool("REQUIRE_VERIFIED_EMAIL", true),
+
+		PasswordResetExpiration: getDuration("PASSWORD_RESET_EXPIRES_IN", time.Hour),
+
+		BcryptCost:           getInt("BCRYPT_COST", 12),
+		LoginWindow:          getDuration("LOGIN_WINDOW", 15*time.Minute),
+		LoginFreeAttempts:    getInt("LOGIN_FREE_ATTEMPTS", 3),
+		LoginMaxAttempts:     getInt("LOGIN_MAX_ATTEMPTS", 10),
+		LoginIPFreeAttempts:  getInt("LOGIN_IP_FREE_ATTEMPTS", 20),
+		LoginIPMaxAttempts:   getInt("LOGIN_IP_MAX_ATTEMPTS", 100),
+		LoginBackoffBase:     getDuration("LOGIN_BACKOFF_BASE", time.Second),
+		LoginBackoffMax:      getDuration("LOGIN_BACKOFF_MAX", 5*time.Minute),
+		LoginLockoutDuration: getDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+
+		TOTPIssuer: getString("TOTP_ISSUER", "Meetup"),
+
+		SMSDriver:        getString("SMS_DRIVER", "fake"),
+		TwilioAccountSID: os.Getenv("TWILIO_ACCOUNT_SID"),
+		TwilioAuthToken:  os.Getenv("TWILIO_AUTH_TOKEN"),
+		TwilioFrom:       os.Getenv("TWILIO_FROM"),
+
+		PhoneOTPExpiration:  getDuration("PHONE_OTP_EXPIRES_IN", 5*time.Minute),
+		PhoneOTPMaxAttempts: getInt("PHONE_OTP_MAX_ATTEMPTS", 5),
+		PhoneOTPResendWait:  getDuration("PHONE_OTP_RESEND_WAIT", time.Minute),
+
+		ReportHideThreshold: getInt("REPORT_HIDE_THRESHOLD", 3),
+		MessageReportWindow: getDuration("MESSAGE_REPORT_WINDOW", 7*24*time.Hour),
+
+		ReviewWindow: getDuration("REVIEW_WINDOW", 14*24*time.Hour),
+
+		MeetupOutcomeWindow: getDuration("MEETUP_OUTCOME_WINDOW", 72*time.Hour),
+
+		PaymentProvider:        strings.ToLower(os.Getenv("PAYMENT_PROVIDER")),
+		PaymentWebhookSecret:   os.Getenv("PAYMENT_WEBHOOK_SECRET"),
+		PaymentOrderExpiration: getDuration("PAYMENT_ORDER_EXPIRES_IN", 30*time.Minute),
+
+		GazetteerPath: getString("GAZETTEER_PATH", "./data/gazetteer.txt"),
+
+		AccountDeletionGracePeriod: getDuration("ACCOUNT_DELETION_GRACE_PERIOD", 14*24*time.Hour),
+
+		OIDCStateExpiration: getDuration("OIDC_STATE_EXPIRES_IN", 10*time.Minute),
 
 		CORSAllowOrigins: []string{"*"},
-		CORSAllowMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		CORSAllowHeaders: []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		CORSAllowMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		CORSAllowHeaders: []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key"},
+	}
+
+	config.OIDCProviders = loadOIDCProviders(config.AppURL)
+
+	// A known secret would let anyone forge a "paid" webhook
+	if config.PaymentProvider != "" && config.PaymentWebhookSecret == "" {
+		log.Fatal("PAYMENT_WEBHOOK_SECRET is required when PAYMENT_PROVIDER is set")
 	}
 
 	return config
 }
+
+// loadOIDCProviders reads the providers listed in OIDC_PROVIDERS.
+// Providers without an issuer or client ID are skipped.
+func loadOIDCProviders(appURL string) []OIDCProvider {
+	var providers []OIDCProvider
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		provider := OIDCProvider{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  getString(prefix+"REDIRECT_URL", appURL+"/api/auth/oidc/"+name+"/callback"),
+			Scopes:       strings.Fields(getString(prefix+"SCOPES", "openid email profile")),
+		}
+		if provider.Issuer == "" || provider.ClientID == "" {
+			log.Printf("OIDC provider %q is missing %sISSUER or %sCLIENT_ID, skipping", name, prefix, prefix)
+			continue
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
+
+// getString reads an environment variable, falling back to the given default when it is empty
+func getString(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getInt reads an integer from the environment
+func getInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getBool reads a boolean such as "true" or "0" from the environment
+func getBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getDuration reads a duration such as "15m" or "72h" from the environment,
+// falling back to the given default when it is missing or malformed
+func getDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fallback
+	}
+	return d
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"log"
+	"meetup_backend/internal/points"
 	"meetup_backend/models"
 
 	"gorm.io/gorm"
@@ -16,6 +17,27 @@ func Migrate(db *gorm.DB) error {
 		&models.Message{},
 		&models.Product{},
 		&models.Category{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.PasswordReset{},
+		&models.RecoveryCode{},
+		&models.LoginAttempt{},
+		&models.SigningKey{},
+		&models.UserIdentity{},
+		&models.OAuthState{},
+		&models.PhoneVerification{},
+		&models.APIKey{},
+		&models.Follow{},
+		&models.Block{},
+		&models.Report{},
+		&models.Meetup{},
+		&models.MeetupParticipant{},
+		&models.Review{},
+		&models.PointTransaction{},
+		&models.PointPackage{},
+		&models.PaymentOrder{},
+		&models.PaymentEvent{},
+		&models.Upload{},
 	)
 
 	if err != nil {
@@ -25,8 +47,15 @@ func Migrate(db *gorm.DB) error {
 
 	log.Println("Database Migrations completed succesfully...")
 
-	// Ensure categories are seeded even on normal migration
+	// Balances from before the points ledger get an opening entry
+	if err := points.BackfillOpeningBalances(db); err != nil {
+		log.Printf("Failed to backfill points ledger: %v", err)
+		return err
+	}
+
+	// Ensure categories and point packages are seeded even on normal migration
 	SeedCategories(db)
+	SeedPointPackages(db)
 
 	return err
 }
@@ -40,6 +69,27 @@ func ResetAndMigrate(db *gorm.DB) error {
 		&models.Message{},
 		&models.Product{},
 		&models.Category{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.PasswordReset{},
+		&models.RecoveryCode{},
+		&models.LoginAttempt{},
+		&models.SigningKey{},
+		&models.UserIdentity{},
+		&models.OAuthState{},
+		&models.PhoneVerification{},
+		&models.APIKey{},
+		&models.Follow{},
+		&models.Block{},
+		&models.Report{},
+		&models.Meetup{},
+		&models.MeetupParticipant{},
+		&models.Review{},
+		&models.PointTransaction{},
+		&models.PointPackage{},
+		&models.PaymentOrder{},
+		&models.PaymentEvent{},
+		&models.Upload{},
 	}
 
 	if err := db.Migrator().DropTable(models...); err != nil {
@@ -56,6 +106,7 @@ func ResetAndMigrate(db *gorm.DB) error {
 
 	// Seed Users
 	SeedCategories(db)
+	SeedPointPackages(db)
 	SeedUsers(db)
 	SeedProducts(db)
 
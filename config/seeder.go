@@ -2,6 +2,7 @@ package config
 
 import (
 	"log"
+	"meetup_backend/internal/points"
 	"meetup_backend/models"
 	"meetup_backend/utils"
 
@@ -15,20 +16,28 @@ func SeedUsers(db *gorm.DB) {
 
 	users := []models.User{
 		{
-			Username: "user1",
-			Email:    "user1@example.com",
-			Password: password,
-			FullName: "User One",
-			Role:     "user",
-			Points:   10,
+			Username:   "user1",
+			Email:      "user1@example.com",
+			Password:   password,
+			FullName:   "User One",
+			Role:       "user",
+			IsVerified: true,
 		},
 		{
-			Username: "user2",
-			Email:    "user2@example.com",
-			Password: password,
-			FullName: "User Two",
-			Role:     "user",
-			Points:   10,
+			Username:   "user2",
+			Email:      "user2@example.com",
+			Password:   password,
+			FullName:   "User Two",
+			Role:       "user",
+			IsVerified: true,
+		},
+		{
+			Username:   "admin",
+			Email:      "admin@example.com",
+			Password:   password,
+			FullName:   "Administrator",
+			Role:       "admin",
+			IsVerified: true,
 		},
 	}
 
@@ -36,7 +45,13 @@ func SeedUsers(db *gorm.DB) {
 		var existingUser models.User
 		if err := db.Where("email = ?", user.Email).First(&existingUser).Error; err != nil {
 			if err == gorm.ErrRecordNotFound {
-				if err := db.Create(&user).Error; err != nil {
+				err := db.Transaction(func(tx *gorm.DB) error {
+					if err := tx.Create(&user).Error; err != nil {
+						return err
+					}
+					return points.GrantSignupBonus(tx, user.ID)
+				})
+				if err != nil {
 					log.Printf("Failed to seed user %s: %v", user.Username, err)
 				} else {
 					log.Printf("User seeded: %s (ID: %d)", user.Username, user.ID)
@@ -168,3 +183,26 @@ func SeedCategories(db *gorm.DB) {
 	}
 	log.Println("✅ Category seeding complete.")
 }
+
+func SeedPointPackages(db *gorm.DB) {
+	log.Println("🌱 Seeding point packages...")
+
+	packages := []models.PointPackage{
+		{Code: "points_10", Name: "10 Points", Points: 10, Price: 10000, Currency: "IDR"},
+		{Code: "points_25", Name: "25 Points", Points: 25, Price: 22500, Currency: "IDR"},
+		{Code: "points_60", Name: "60 Points", Points: 60, Price: 50000, Currency: "IDR"},
+	}
+
+	for _, p := range packages {
+		var count int64
+		db.Model(&models.PointPackage{}).Where("code = ?", p.Code).Count(&count)
+		if count == 0 {
+			if err := db.Create(&p).Error; err != nil {
+				log.Printf("Failed to seed point package %s: %v", p.Code, err)
+			} else {
+				log.Printf("Point package seeded: %s", p.Name)
+			}
+		}
+	}
+	log.Println("✅ Point package seeding complete.")
+}
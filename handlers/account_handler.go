@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"meetup_backend/config"
+	"meetup_backend/internal/ws"
+	"meetup_backend/models"
+	"meetup_backend/utils"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type AccountHandler struct {
+	DB     *gorm.DB
+	Config *config.Config
+	Hub    *ws.Hub
+}
+
+func NewAccountHandler(db *gorm.DB, cfg *config.Config, hub *ws.Hub) *AccountHandler {
+	return &AccountHandler{DB: db, Config: cfg, Hub: hub}
+}
+
+// DeleteAccountRequest confirms an account deletion. Accounts without a password
+// (social login only) confirm by typing their username instead.
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+	Confirm  string `json:"confirm"`
+}
+
+// ExportData - GET /api/users/me/export
+// Returns a ZIP archive with one JSON file per kind of personal data
+func (h *AccountHandler) ExportData(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var user models.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	var products []models.Product
+	var participations []models.ChatParticipant
+	var messages []models.Message
+	var sessions []models.Session
+	var identities []models.UserIdentity
+	var apiKeys []models.APIKey
+	var loginAttempts []models.LoginAttempt
+	var follows []models.Follow
+	var blocks []models.Block
+	var reports []models.Report
+	var meetups []models.Meetup
+	var reviews []models.Review
+	var pointTransactions []models.PointTransaction
+	var paymentOrders []models.PaymentOrder
+
+	roomIDs := h.DB.Model(&models.ChatParticipant{}).Select("chat_room_id").Where("user_id = ?", userID)
+	queries := []*gorm.DB{
+		h.DB.Unscoped().Where("seller_id = ?", userID).Order("id").Find(&products),
+		h.DB.Preload("ChatRoom").Where("user_id = ?", userID).Order("id").Find(&participations),
+		// Messages they sent, plus everything stored in the rooms they are still in
+		h.DB.Where("sender_id = ? OR chat_room_id IN (?)", userID, roomIDs).Order("id").Find(&messages),
+		h.DB.Where("user_id = ?", userID).Order("id").Find(&sessions),
+		h.DB.Where("user_id = ?", userID).Order("id").Find(&identities),
+		h.DB.Where("user_id = ?", userID).Order("id").Find(&apiKeys),
+		h.DB.Where("user_id = ? OR email = ?", userID, user.Email).Order("id").Find(&loginAttempts),
+		h.DB.Where("follower_id = ? OR following_id = ?", userID, userID).Order("id").Find(&follows),
+		h.DB.Where("blocker_id = ?", userID).Order("id").Find(&blocks),
+		// Reports they filed; moderator notes and assignment are internal
+		h.DB.Select("id, reporter_id, target_type, target_id, reported_user_id, reason, details, message_snapshot, status, created_at, updated_at").
+			Where("reporter_id = ?", userID).Order("id").Find(&reports),
+		// Which moderator settled a dispute is internal
+		h.DB.Preload("Participants").Omit("resolved_by").
+			Where("id IN (?)", h.DB.Model(&models.MeetupParticipant{}).Select("meetup_id").Where("user_id = ?", userID)).
+			Order("id").Find(&meetups),
+		// Reviews they wrote, and received ones that are already visible
+		h.DB.Where("reviewer_id = ? OR (reviewee_id = ? AND visible_at <= ?)", userID, userID, time.Now()).Order("id").Find(&reviews),
+		h.DB.Where("user_id = ?", userID).Order("id").Find(&pointTransactions),
+		h.DB.Where("user_id = ?", userID).Order("id").Find(&paymentOrders),
+	}
+	for _, q := range queries {
+		if q.Error != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not export data"})
+		}
+	}
+
+	// Sender is preloaded nowhere, so blank it instead of exporting empty users
+	exportedMessages := make([]fiber.Map, 0, len(messages))
+	for _, m := range messages {
+		exportedMessages = append(exportedMessages, fiber.Map{
+			"id":           m.ID,
+			"chat_room_id": m.ChatRoomID,
+			"sender_id":    m.SenderID,
+			"sent_by_me":   m.SenderID == userID,
+			"content":      m.Content,
+			"media_type":   m.MediaType,
+			"media_url":    m.MediaURL,
+			"product_info": m.ProductInfo,
+			"is_read":      m.IsRead,
+			"created_at":   m.CreatedAt,
+		})
+	}
+
+	exportedParticipations := make([]fiber.Map, 0, len(participations))
+	for _, p := range participations {
+		exportedParticipations = append(exportedParticipations, fiber.Map{
+			"chat_room_id": p.ChatRoomID,
+			"room_type":    p.ChatRoom.Type,
+			"room_name":    p.ChatRoom.Name,
+			"role":         p.Role,
+			"joined_at":    p.JoinedAt,
+		})
+	}
+
+	for i := range products {
+		products[i].Seller = models.User{}
+	}
+
+	keys := make([]APIKeyResult, 0, len(apiKeys))
+	for i := range apiKeys {
+		keys = append(keys, newAPIKeyResult(&apiKeys[i]))
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", user},
+		{"products.json", products},
+		{"chat_participations.json", exportedParticipations},
+		{"messages.json", exportedMessages},
+		{"points.json", fiber.Map{"balance": user.Points, "transactions": pointTransactions}},
+		{"sessions.json", sessions},
+		{"linked_accounts.json", identities},
+		{"api_keys.json", keys},
+		{"login_attempts.json", loginAttempts},
+		{"follows.json", follows},
+		{"blocked_users.json", blocks},
+		{"reports.json", reports},
+		{"meetups.json", meetups},
+		{"reviews.json", reviews},
+		{"payment_orders.json", paymentOrders},
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := archive.Create(f.name)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not export data"})
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(f.data); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not export data"})
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not export data"})
+	}
+
+	filename := fmt.Sprintf("meetup-export-%d-%s.zip", userID, time.Now().Format("20060102"))
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Send(buf.Bytes())
+}
+
+// RequestDeletion - POST /api/users/me/deletion
+// Schedules the account for deletion after the grace period and signs out everywhere.
+// Logging in again during the grace period is allowed so the request can be cancelled.
+func (h *AccountHandler) RequestDeletion(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req DeleteAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if user.DeletionScheduledAt != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":                 "Account deletion is already scheduled",
+			"deletion_scheduled_at": user.DeletionScheduledAt,
+		})
+	}
+
+	if user.Password != "" {
+		if !utils.CheckPasswordHash(req.Password, user.Password) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Password is incorrect"})
+		}
+	} else if !strings.EqualFold(strings.TrimSpace(req.Confirm), user.Username) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Type your username in 'confirm' to delete your account"})
+	}
+
+	now := time.Now()
+	scheduledAt := now.Add(h.Config.AccountDeletionGracePeriod)
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"deletion_requested_at": now,
+			"deletion_scheduled_at": scheduledAt,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.APIKey{}).Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return utils.RevokeUserSessions(tx, userID, 0, "account_deletion")
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not schedule account deletion"})
+	}
+
+	h.Hub.DisconnectUser(userID, "account_deletion")
+	log.Printf("User %d scheduled account deletion for %s", userID, scheduledAt.Format(time.RFC3339))
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "Your account will be deleted. Log in and cancel the deletion before the scheduled time to keep it.",
+		"data": fiber.Map{
+			"deletion_scheduled_at": scheduledAt,
+		},
+	})
+}
+
+// CancelDeletion - DELETE /api/users/me/deletion
+func (h *AccountHandler) CancelDeletion(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	result := h.DB.Model(&models.User{}).
+		Where("id = ? AND deletion_scheduled_at IS NOT NULL", userID).
+		Updates(map[string]interface{}{
+			"deletion_requested_at": nil,
+			"deletion_scheduled_at": nil,
+		})
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not cancel account deletion"})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No account deletion is scheduled"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Account deletion cancelled"})
+}
@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"meetup_backend/internal/points"
+	"meetup_backend/internal/ws"
+	"meetup_backend/models"
+	"meetup_backend/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type AdminHandler struct {
+	DB     *gorm.DB
+	Hub    *ws.Hub
+	Points *points.Service
+}
+
+func NewAdminHandler(db *gorm.DB, hub *ws.Hub, pointsService *points.Service) *AdminHandler {
+	return &AdminHandler{DB: db, Hub: hub, Points: pointsService}
+}
+
+// BanUserRequest defines the payload for banning/suspending a user.
+// ExpiresAt empty means a permanent ban.
+type BanUserRequest struct {
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// ChangeRoleRequest defines the payload for changing a user's role
+type ChangeRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// ResetPointsRequest defines the payload for resetting a user's points
+type ResetPointsRequest struct {
+	Points *int   `json:"points"` // Defaults to the registration bonus (10)
+	Note   string `json:"note"`   // Stored in the points ledger
+}
+
+// getPagination reads ?page and ?limit with sane bounds
+func getPagination(c *fiber.Ctx) (int, int) {
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := c.QueryInt("limit", 20)
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return page, limit
+}
+
+// ListUsers - GET /api/admin/users
+// Filters: q (username/email/full name), role, verified (true/false), status (active/banned)
+func (h *AdminHandler) ListUsers(c *fiber.Ctx) error {
+	page, limit := getPagination(c)
+
+	query := h.DB.Model(&models.User{})
+
+	if q := c.Query("q"); q != "" {
+		like := "%" + q + "%"
+		query = query.Where("username LIKE ? OR email LIKE ? OR full_name LIKE ?", like, like, like)
+	}
+	if role := c.Query("role"); role != "" {
+		query = query.Where("role = ?", role)
+	}
+	if verified := c.Query("verified"); verified != "" {
+		query = query.Where("is_verified = ?", verified == "true")
+	}
+	switch c.Query("status") {
+	case "banned":
+		query = query.Where("banned_at IS NOT NULL AND (banned_until IS NULL OR banned_until > ?)", time.Now())
+	case "active":
+		query = query.Where("banned_at IS NULL OR (banned_until IS NOT NULL AND banned_until <= ?)", time.Now())
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch users"})
+	}
+
+	var users []models.User
+	if err := query.Order("created_at desc").
+		Limit(limit).
+		Offset((page - 1) * limit).
+		Find(&users).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch users"})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": users,
+		"meta": models.NewPaginationMeta(page, limit, total),
+	})
+}
+
+// GetUser - GET /api/admin/users/:id
+// Returns the user with their products, chat rooms and points
+func (h *AdminHandler) GetUser(c *fiber.Ctx) error {
+	user, err := h.findUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	var products []models.Product
+	if err := h.DB.Unscoped().Where("seller_id = ?", user.ID).Order("created_at desc").Find(&products).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch products"})
+	}
+
+	type ChatResult struct {
+		ID            uint       `json:"id"`
+		Type          string     `json:"type"`
+		LastMessageAt *time.Time `json:"last_message_at"`
+		JoinedAt      time.Time  `json:"joined_at"`
+		Left          bool       `json:"left"`
+		OtherUserIDs  []uint     `json:"other_user_ids"`
+	}
+
+	var participations []models.ChatParticipant
+	if err := h.DB.Unscoped().Preload("ChatRoom").Where("user_id = ?", user.ID).Find(&participations).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch chats"})
+	}
+
+	chats := make([]ChatResult, 0, len(participations))
+	for _, p := range participations {
+		var others []uint
+		h.DB.Unscoped().Model(&models.ChatParticipant{}).
+			Where("chat_room_id = ? AND user_id != ?", p.ChatRoomID, user.ID).
+			Pluck("user_id", &others)
+
+		chats = append(chats, ChatResult{
+			ID:            p.ChatRoomID,
+			Type:          p.ChatRoom.Type,
+			LastMessageAt: p.ChatRoom.LastMessageAt,
+			JoinedAt:      p.JoinedAt,
+			Left:          p.DeletedAt.Valid,
+			OtherUserIDs:  others,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"user":     user,
+			"points":   user.Points,
+			"products": products,
+			"chats":    chats,
+		},
+	})
+}
+
+// BanUser - POST /api/admin/users/:id/ban
+// Bans (no expiry) or suspends (with expires_at) a user, logs out all devices and drops live connections
+func (h *AdminHandler) BanUser(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(uint)
+
+	var req BanUserRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	if req.Reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Reason is required"})
+	}
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "expires_at must be in the future"})
+	}
+
+	user, err := h.findUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if user.ID == adminID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "You cannot ban yourself"})
+	}
+
+	if err := utils.BanUser(h.DB, user, adminID, req.Reason, req.ExpiresAt); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not ban user"})
+	}
+
+	h.Hub.DisconnectUser(user.ID, "banned")
+	log.Printf("User %d banned by admin %d (until: %v, reason: %s)", user.ID, adminID, req.ExpiresAt, req.Reason)
+
+	return c.JSON(fiber.Map{"message": "User banned", "data": user})
+}
+
+// UnbanUser - POST /api/admin/users/:id/unban
+func (h *AdminHandler) UnbanUser(c *fiber.Ctx) error {
+	user, err := h.findUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if err := h.DB.Model(user).Updates(map[string]interface{}{
+		"banned_at":    nil,
+		"banned_until": nil,
+		"ban_reason":   "",
+		"banned_by":    nil,
+	}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not unban user"})
+	}
+
+	return c.JSON(fiber.Map{"message": "User unbanned", "data": user})
+}
+
+// VerifyUser - POST /api/admin/users/:id/verify
+// Marks the user's email as verified without the email flow
+func (h *AdminHandler) VerifyUser(c *fiber.Ctx) error {
+	user, err := h.findUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if err := h.DB.Model(user).Updates(map[string]interface{}{
+		"is_verified":       true,
+		"email_verified_at": time.Now(),
+	}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not verify user"})
+	}
+
+	return c.JSON(fiber.Map{"message": "User verified", "data": user})
+}
+
+// ChangeRole - PUT /api/admin/users/:id/role
+func (h *AdminHandler) ChangeRole(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(uint)
+
+	var req ChangeRoleRequest
+	if err := c.BodyParser(&req); err != nil || !utils.IsValidRole(req.Role) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Role must be one of: user, moderator, admin"})
+	}
+
+	user, err := h.findUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if user.ID == adminID {
+		// Prevents the last admin from locking everyone out by accident
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "You cannot change your own role"})
+	}
+
+	if err := h.DB.Model(user).Update("role", req.Role).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not change role"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Role updated", "data": user})
+}
+
+// ResetPoints - POST /api/admin/users/:id/reset-points
+func (h *AdminHandler) ResetPoints(c *fiber.Ctx) error {
+	var req ResetPointsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	balance := points.SignupBonus
+	if req.Points != nil {
+		balance = *req.Points
+	}
+	if balance < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Points cannot be negative"})
+	}
+
+	user, err := h.findUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	adminID := c.Locals("user_id").(uint)
+	entry, err := h.Points.SetBalance(user.ID, balance, points.Change{
+		Reason:  points.ReasonAdminReset,
+		Note:    req.Note,
+		ActorID: &adminID,
+	})
+	if errors.Is(err, points.ErrZeroDelta) {
+		user.Points = balance
+		return c.JSON(fiber.Map{"message": "Points already at that balance", "data": user, "transaction": nil})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not reset points"})
+	}
+	user.Points = entry.BalanceAfter
+
+	return c.JSON(fiber.Map{"message": "Points reset", "data": user, "transaction": entry})
+}
+
+// ListLoginAttempts - GET /api/admin/login-attempts
+// Audit log of login attempts. Filters: email, ip, user_id, success (true/false)
+func (h *AdminHandler) ListLoginAttempts(c *fiber.Ctx) error {
+	page, limit := getPagination(c)
+
+	query := h.DB.Model(&models.LoginAttempt{})
+	if email := c.Query("email"); email != "" {
+		query = query.Where("email = ?", email)
+	}
+	if ip := c.Query("ip"); ip != "" {
+		query = query.Where("ip_address = ?", ip)
+	}
+	if userID := c.QueryInt("user_id"); userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	if success := c.Query("success"); success != "" {
+		query = query.Where("success = ?", success == "true")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch login attempts"})
+	}
+
+	var attempts []models.LoginAttempt
+	if err := query.Order("created_at desc").
+		Limit(limit).
+		Offset((page - 1) * limit).
+		Find(&attempts).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch login attempts"})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": attempts,
+		"meta": models.NewPaginationMeta(page, limit, total),
+	})
+}
+
+// findUser loads the user from the :id route param
+func (h *AdminHandler) findUser(c *fiber.Ctx) (*models.User, error) {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
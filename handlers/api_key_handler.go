@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"fmt"
+	"meetup_backend/models"
+	"meetup_backend/utils"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type APIKeyHandler struct {
+	DB *gorm.DB
+}
+
+func NewAPIKeyHandler(db *gorm.DB) *APIKeyHandler {
+	return &APIKeyHandler{DB: db}
+}
+
+// CreateAPIKeyRequest defines the payload for creating an API key
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays *int     `json:"expires_in_days"` // Default 90, 0 = never expires
+}
+
+// APIKeyResult is an API key as shown to its owner (never includes the secret)
+type APIKeyResult struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+const (
+	maxAPIKeysPerUser       = 20
+	defaultAPIKeyExpiryDays = 90
+	maxAPIKeyExpiryDays     = 365
+)
+
+func newAPIKeyResult(k *models.APIKey) APIKeyResult {
+	return APIKeyResult{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.ScopeList(),
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		LastUsedIP: k.LastUsedIP,
+		CreatedAt:  k.CreatedAt,
+	}
+}
+
+// ListKeys - GET /api/api-keys
+// Lists the current user's keys that are not revoked
+func (h *APIKeyHandler) ListKeys(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var keys []models.APIKey
+	if err := h.DB.Where("user_id = ? AND revoked_at IS NULL", userID).Order("created_at desc").Find(&keys).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch API keys"})
+	}
+
+	results := make([]APIKeyResult, 0, len(keys))
+	for i := range keys {
+		results = append(results, newAPIKeyResult(&keys[i]))
+	}
+
+	return c.JSON(fiber.Map{
+		"data":   results,
+		"scopes": utils.APIKeyScopes,
+	})
+}
+
+// CreateKey - POST /api/api-keys
+// The full key is only returned in this response
+func (h *APIKeyHandler) CreateKey(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Name is required (max 100 characters)"})
+	}
+
+	if len(req.Scopes) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "At least one scope is required", "scopes": utils.APIKeyScopes})
+	}
+	scopes := make([]string, 0, len(req.Scopes))
+	seen := make(map[string]bool, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if !utils.IsValidScope(scope) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("Unknown scope %q", scope), "scopes": utils.APIKeyScopes})
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	days := defaultAPIKeyExpiryDays
+	if req.ExpiresInDays != nil {
+		days = *req.ExpiresInDays
+	}
+	if days < 0 || days > maxAPIKeyExpiryDays {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("expires_in_days must be between 0 and %d", maxAPIKeyExpiryDays)})
+	}
+
+	var count int64
+	h.DB.Model(&models.APIKey{}).Where("user_id = ? AND revoked_at IS NULL", userID).Count(&count)
+	if count >= maxAPIKeysPerUser {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("You can have at most %d API keys. Revoke one first.", maxAPIKeysPerUser)})
+	}
+
+	secret, prefix, err := utils.GenerateAPIKey()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create API key"})
+	}
+
+	key := models.APIKey{
+		UserID:  userID,
+		Name:    req.Name,
+		Prefix:  prefix,
+		KeyHash: utils.HashToken(secret),
+		Scopes:  strings.Join(scopes, " "),
+	}
+	if days > 0 {
+		expiresAt := time.Now().AddDate(0, 0, days)
+		key.ExpiresAt = &expiresAt
+	}
+
+	if err := h.DB.Create(&key).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create API key"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "API key created. Copy it now, it will not be shown again.",
+		"key":     secret,
+		"data":    newAPIKeyResult(&key),
+	})
+}
+
+// RevokeKey - DELETE /api/api-keys/:id
+func (h *APIKeyHandler) RevokeKey(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	keyID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid API key ID"})
+	}
+
+	result := h.DB.Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", keyID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not revoke API key"})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "API key not found"})
+	}
+
+	return c.JSON(fiber.Map{"message": "API key revoked"})
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"meetup_backend/config"
+	"meetup_backend/internal/mailer"
+	"meetup_backend/internal/token"
+	"meetup_backend/internal/ws"
+	"meetup_backend/models"
+	"meetup_backend/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+var linkPattern = regexp.MustCompile(`https?://\S+`)
+
+// newAuthTestApp serves the email flows of AuthHandler from an in-memory database,
+// sending emails to the returned FakeMailer
+func newAuthTestApp(t *testing.T) (*fiber.App, *gorm.DB, *mailer.FakeMailer) {
+	t.Helper()
+
+	db := newTestDB(t, &models.User{}, &models.Session{}, &models.PasswordReset{}, &models.SigningKey{}, &models.PointTransaction{})
+
+	tokens, err := token.NewService(db, token.Options{
+		Algorithm:        "EdDSA",
+		Issuer:           "meetup-test",
+		Audience:         "meetup-test",
+		RotationInterval: time.Hour,
+		VerifyGrace:      time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hub := ws.NewHub()
+	go hub.Run()
+
+	cfg := &config.Config{
+		AppURL:                      "http://meetup.test",
+		EmailVerificationExpiration: time.Hour,
+		PasswordResetExpiration:     time.Hour,
+	}
+	fakeMailer := mailer.NewFakeMailer("")
+	h := NewAuthHandler(db, cfg, hub, fakeMailer, tokens)
+
+	app := fiber.New()
+	app.Post("/api/auth/register", h.Register)
+	app.Get("/api/auth/verify", h.VerifyEmail)
+	app.Post("/api/auth/forgot-password", h.ForgotPassword)
+	app.Post("/api/auth/reset-password", h.ResetPassword)
+
+	return app, db, fakeMailer
+}
+
+// tokenFromEmail returns the token query parameter of the link in the latest email to the address
+func tokenFromEmail(t *testing.T, m *mailer.FakeMailer, to string) string {
+	t.Helper()
+
+	msg, ok := m.LastTo(to)
+	if !ok {
+		t.Fatalf("no email sent to %s", to)
+	}
+	link, err := url.Parse(linkPattern.FindString(msg.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok := link.Query().Get("token")
+	if tok == "" {
+		t.Fatalf("no token in email body: %q", msg.Body)
+	}
+	return tok
+}
+
+// waitForEmails waits for emails sent in the background
+func waitForEmails(t *testing.T, m *mailer.FakeMailer, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(m.Messages()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d emails, want %d", len(m.Messages()), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func register(t *testing.T, app *fiber.App, username, email string) {
+	t.Helper()
+
+	status, body := doJSON(t, app, "POST", "/api/auth/register", RegisterRequest{
+		Username: username,
+		Email:    email,
+		Password: "old-password",
+		FullName: "Test User",
+	})
+	if status != fiber.StatusCreated {
+		t.Fatalf("register returned %d: %v", status, body)
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	app, db, m := newAuthTestApp(t)
+	register(t, app, "alice", "alice@example.com")
+
+	tok := tokenFromEmail(t, m, "alice@example.com")
+
+	if status, _ := doJSON(t, app, "GET", "/api/auth/verify?token=tampered"+tok, nil); status != fiber.StatusBadRequest {
+		t.Fatalf("tampered token: got %d, want %d", status, fiber.StatusBadRequest)
+	}
+
+	status, body := doJSON(t, app, "GET", "/api/auth/verify?token="+url.QueryEscape(tok), nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("verify returned %d: %v", status, body)
+	}
+
+	var user models.User
+	db.Where("email = ?", "alice@example.com").First(&user)
+	if !user.IsVerified || user.EmailVerifiedAt == nil {
+		t.Fatal("user is not verified after opening the link")
+	}
+
+	// The link stays harmless once used
+	status, body = doJSON(t, app, "GET", "/api/auth/verify?token="+url.QueryEscape(tok), nil)
+	if status != fiber.StatusOK || body["message"] != "Email already verified" {
+		t.Fatalf("second verify returned %d: %v", status, body)
+	}
+}
+
+func TestVerifyEmailRejectsLinkForOldAddress(t *testing.T) {
+	app, db, m := newAuthTestApp(t)
+	register(t, app, "bob", "bob@example.com")
+
+	tok := tokenFromEmail(t, m, "bob@example.com")
+	db.Model(&models.User{}).Where("email = ?", "bob@example.com").Update("email", "bob@new.example.com")
+
+	if status, _ := doJSON(t, app, "GET", "/api/auth/verify?token="+url.QueryEscape(tok), nil); status != fiber.StatusBadRequest {
+		t.Fatalf("got %d, want %d", status, fiber.StatusBadRequest)
+	}
+
+	var user models.User
+	db.Where("email = ?", "bob@new.example.com").First(&user)
+	if user.IsVerified {
+		t.Fatal("new address was verified with a link sent to the old one")
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	app, db, m := newAuthTestApp(t)
+	register(t, app, "carol", "carol@example.com")
+
+	var user models.User
+	db.Where("email = ?", "carol@example.com").First(&user)
+	db.Create(&models.Session{UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)})
+
+	status, _ := doJSON(t, app, "POST", "/api/auth/forgot-password", ForgotPasswordRequest{Email: "carol@example.com"})
+	if status != fiber.StatusOK {
+		t.Fatalf("forgot-password returned %d", status)
+	}
+	waitForEmails(t, m, 2) // Verification and reset
+	tok := tokenFromEmail(t, m, "carol@example.com")
+
+	status, body := doJSON(t, app, "POST", "/api/auth/reset-password", ResetPasswordRequest{Token: tok, NewPassword: "new-password"})
+	if status != fiber.StatusOK {
+		t.Fatalf("reset-password returned %d: %v", status, body)
+	}
+
+	db.First(&user, user.ID)
+	if !utils.CheckPasswordHash("new-password", user.Password) {
+		t.Fatal("password was not changed")
+	}
+
+	var active int64
+	db.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).Count(&active)
+	if active != 0 {
+		t.Fatalf("%d session(s) still active after the reset", active)
+	}
+
+	// Reset links are single-use
+	status, _ = doJSON(t, app, "POST", "/api/auth/reset-password", ResetPasswordRequest{Token: tok, NewPassword: "another-password"})
+	if status != fiber.StatusBadRequest {
+		t.Fatalf("reused token: got %d, want %d", status, fiber.StatusBadRequest)
+	}
+}
+
+func TestPasswordResetOnlyLatestLinkIsValid(t *testing.T) {
+	app, _, m := newAuthTestApp(t)
+	register(t, app, "dave", "dave@example.com")
+
+	doJSON(t, app, "POST", "/api/auth/forgot-password", ForgotPasswordRequest{Email: "dave@example.com"})
+	waitForEmails(t, m, 2)
+	first := tokenFromEmail(t, m, "dave@example.com")
+
+	doJSON(t, app, "POST", "/api/auth/forgot-password", ForgotPasswordRequest{Email: "dave@example.com"})
+	waitForEmails(t, m, 3)
+	second := tokenFromEmail(t, m, "dave@example.com")
+
+	if status, _ := doJSON(t, app, "POST", "/api/auth/reset-password", ResetPasswordRequest{Token: first, NewPassword: "new-password"}); status != fiber.StatusBadRequest {
+		t.Fatalf("earlier link: got %d, want %d", status, fiber.StatusBadRequest)
+	}
+	if status, body := doJSON(t, app, "POST", "/api/auth/reset-password", ResetPasswordRequest{Token: second, NewPassword: "new-password"}); status != fiber.StatusOK {
+		t.Fatalf("latest link returned %d: %v", status, body)
+	}
+}
+
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	app, _, m := newAuthTestApp(t)
+
+	status, _ := doJSON(t, app, "POST", "/api/auth/forgot-password", ForgotPasswordRequest{Email: "nobody@example.com"})
+	if status != fiber.StatusOK {
+		t.Fatalf("got %d, want %d", status, fiber.StatusOK)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := len(m.Messages()); n != 0 {
+		t.Fatalf("%d email(s) sent for an unknown address", n)
+	}
+}
@@ -1,10 +1,18 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"meetup_backend/config"
+	"meetup_backend/internal/mailer"
+	"meetup_backend/internal/oidc"
+	"meetup_backend/internal/points"
+	"meetup_backend/internal/token"
+	"meetup_backend/internal/ws"
 	"meetup_backend/models"
-
 	"meetup_backend/utils"
-	"os"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -13,11 +21,52 @@ import (
 )
 
 type AuthHandler struct {
-	DB *gorm.DB
+	DB       *gorm.DB
+	Config   *config.Config
+	Hub      *ws.Hub
+	Mailer   mailer.Mailer
+	Tokens   *token.Service
+	Throttle *utils.LoginThrottle
+	OIDC     map[string]*oidc.Provider // Social login providers by name
+}
+
+func NewAuthHandler(db *gorm.DB, cfg *config.Config, hub *ws.Hub, m mailer.Mailer, tokens *token.Service) *AuthHandler {
+	providers := make(map[string]*oidc.Provider, len(cfg.OIDCProviders))
+	for _, p := range cfg.OIDCProviders {
+		providers[p.Name] = oidc.NewProvider(oidc.Config{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		})
+	}
+
+	return &AuthHandler{
+		DB:       db,
+		Config:   cfg,
+		Hub:      hub,
+		Mailer:   m,
+		Tokens:   tokens,
+		Throttle: newLoginThrottle(db, cfg),
+		OIDC:     providers,
+	}
 }
 
-func NewAuthHandler(db *gorm.DB) *AuthHandler {
-	return &AuthHandler{DB: db}
+// newLoginThrottle builds the throttle shared by every check of a password or second factor
+func newLoginThrottle(db *gorm.DB, cfg *config.Config) *utils.LoginThrottle {
+	return &utils.LoginThrottle{
+		DB:                 db,
+		Window:             cfg.LoginWindow,
+		FreeAttempts:       cfg.LoginFreeAttempts,
+		AccountMaxAttempts: cfg.LoginMaxAttempts,
+		IPFreeAttempts:     cfg.LoginIPFreeAttempts,
+		IPMaxAttempts:      cfg.LoginIPMaxAttempts,
+		BaseDelay:          cfg.LoginBackoffBase,
+		MaxDelay:           cfg.LoginBackoffMax,
+		LockoutDuration:    cfg.LoginLockoutDuration,
+	}
 }
 
 // RegisterRequest defines the payload for registration
@@ -30,10 +79,51 @@ type RegisterRequest struct {
 
 // LoginRequest defines the payload for login
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	DeviceName string `json:"device_name"` // Optional, shown in the active sessions list
+}
+
+// RefreshRequest defines the payload for rotating a refresh token
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// LoginTwoFactorRequest defines the payload for the second login step.
+// Either Code (from the authenticator app) or RecoveryCode must be set.
+type LoginTwoFactorRequest struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// ForgotPasswordRequest defines the payload for requesting a reset link
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
 }
 
+// ResetPasswordRequest defines the payload for setting a new password with a reset token
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// ChangePasswordRequest defines the payload for changing the password while logged in
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+const (
+	minPasswordLength      = 8
+	mfaChallengeExpiration = 5 * time.Minute
+)
+
+var (
+	errRefreshTokenReused = errors.New("refresh token reuse detected")
+	errUserExists         = errors.New("user already exists")
+)
+
 func (h *AuthHandler) Register(c *fiber.Ctx) error {
 	var req RegisterRequest
 	if err := c.BodyParser(&req); err != nil {
@@ -52,14 +142,114 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 		Password: hashedPassword,
 		FullName: req.FullName,
 		Role:     "user",
-		Points:   10,
 	}
 
-	if err := h.DB.Create(&user).Error; err != nil {
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return errUserExists
+		}
+		return points.GrantSignupBonus(tx, user.ID)
+	})
+	if errors.Is(err, errUserExists) {
 		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "User already exists"})
 	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create user"})
+	}
+
+	// Registration succeeds even if the email cannot be sent; the user can ask for a resend
+	if err := h.sendVerificationEmail(&user); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "User registered successfully. Please check your email to verify your account."})
+}
+
+// VerifyEmail - GET /api/auth/verify?token=...
+func (h *AuthHandler) VerifyEmail(c *fiber.Ctx) error {
+	claims, err := h.Tokens.ParseActionToken(c.Query("token"), "email_verify")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Verification link is invalid or has expired"})
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, utils.ClaimUint(claims, "user_id")).Error; err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Verification link is invalid or has expired"})
+	}
+
+	// The link is bound to the address it was sent to
+	if email, _ := claims["email"].(string); email != user.Email {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Verification link is invalid or has expired"})
+	}
+
+	if user.IsVerified {
+		return c.JSON(fiber.Map{"message": "Email already verified"})
+	}
+
+	now := time.Now()
+	if err := h.DB.Model(&user).Updates(map[string]interface{}{
+		"is_verified":       true,
+		"email_verified_at": now,
+	}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not verify email"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Email verified successfully"})
+}
+
+// ResendVerification - POST /api/auth/verify/resend
+func (h *AuthHandler) ResendVerification(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var user models.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if user.IsVerified {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Email already verified"})
+	}
+
+	// Throttle resends
+	if user.VerificationSentAt != nil {
+		wait := time.Until(user.VerificationSentAt.Add(h.Config.EmailVerificationResendWait))
+		if wait > 0 {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error":       "Please wait before requesting another verification email",
+				"retry_after": int(wait.Seconds()) + 1,
+			})
+		}
+	}
+
+	if err := h.sendVerificationEmail(&user); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not send verification email"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Verification email sent"})
+}
+
+// sendVerificationEmail signs a verification link for the user's current email and mails it
+func (h *AuthHandler) sendVerificationEmail(user *models.User) error {
+	token, err := h.Tokens.IssueActionToken("email_verify", user.ID, jwt.MapClaims{"email": user.Email}, h.Config.EmailVerificationExpiration)
+	if err != nil {
+		return err
+	}
 
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "User registered successfully"})
+	link := fmt.Sprintf("%s/api/auth/verify?token=%s", h.Config.AppURL, token)
+	err = h.Mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease verify your email address by opening the link below:\n\n%s\n\nThis link expires in %s.\n",
+			user.Username, link, h.Config.EmailVerificationExpiration),
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user.VerificationSentAt = &now
+	return h.DB.Model(user).Update("verification_sent_at", now).Error
 }
 
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
@@ -68,37 +258,509 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
 	}
 
+	ip, userAgent := c.IP(), c.Get(fiber.HeaderUserAgent)
+
+	// Brute-force protection (per account and per IP)
+	attempt, wait, locked := h.Throttle.Reserve(req.Email, ip, userAgent)
+	if wait > 0 {
+		return throttledResponse(c, wait, locked)
+	}
+
 	var user models.User
 	if err := h.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		attempt.Fail(nil, "unknown_email")
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid credentials"})
 	}
 
 	// Verify password
 	if !utils.CheckPasswordHash(req.Password, user.Password) {
+		attempt.Fail(&user.ID, "bad_password")
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid credentials"})
 	}
 
-	// Generate JWT
-	claims := jwt.MapClaims{
-		"user_id": user.ID,
-		"role":    user.Role,
-		"exp":     time.Now().Add(time.Hour * 72).Unix(),
+	if user.IsBanned() {
+		attempt.Cancel()
+		return c.Status(fiber.StatusForbidden).JSON(utils.BannedResponse(&user))
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	t, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+
+	// Upgrade hashes created with an older, weaker bcrypt cost while we have the plain password
+	if utils.NeedsRehash(user.Password) {
+		if hashed, err := utils.HashPassword(req.Password); err == nil {
+			if err := h.DB.Model(&user).Update("password", hashed).Error; err != nil {
+				log.Printf("Failed to rehash password for user %d: %v", user.ID, err)
+			}
+		}
+	}
+
+	// With 2FA enabled the password alone is not enough
+	if user.TOTPEnabled {
+		attempt.Cancel()
+		return h.mfaChallenge(c, &user, req.DeviceName)
+	}
+
+	attempt.Succeed(user.ID)
+	return h.completeLogin(c, &user, req.DeviceName)
+}
+
+// mfaChallenge hands out a short-lived challenge token that must be exchanged
+// together with a TOTP or recovery code at /login/2fa
+func (h *AuthHandler) mfaChallenge(c *fiber.Ctx, user *models.User, deviceName string) error {
+	mfaToken, err := h.Tokens.IssueActionToken("mfa_challenge", user.ID, jwt.MapClaims{"device_name": deviceName}, mfaChallengeExpiration)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not login"})
+	}
+	return c.JSON(fiber.Map{
+		"mfa_required": true,
+		"mfa_token":    mfaToken,
+	})
+}
+
+// LoginTwoFactor - POST /api/auth/login/2fa
+// Second login step: exchanges the MFA challenge token plus a TOTP or recovery code for real tokens
+func (h *AuthHandler) LoginTwoFactor(c *fiber.Ctx) error {
+	var req LoginTwoFactorRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	claims, err := h.Tokens.ParseActionToken(req.MFAToken, "mfa_challenge")
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "MFA challenge is invalid or has expired. Please login again."})
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, utils.ClaimUint(claims, "user_id")).Error; err != nil || !user.TOTPEnabled {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "MFA challenge is invalid or has expired. Please login again."})
+	}
+	if user.IsBanned() {
+		return c.Status(fiber.StatusForbidden).JSON(utils.BannedResponse(&user))
+	}
+
+	ip, userAgent := c.IP(), c.Get(fiber.HeaderUserAgent)
+	attempt, wait, locked := h.Throttle.Reserve(user.Email, ip, userAgent)
+	if wait > 0 {
+		return throttledResponse(c, wait, locked)
+	}
+
+	verified := false
+	if req.Code != "" {
+		verified = utils.VerifyUserTOTP(h.DB, &user, req.Code)
+	} else if req.RecoveryCode != "" {
+		verified = utils.ConsumeRecoveryCode(h.DB, user.ID, req.RecoveryCode)
+	}
+	if !verified {
+		attempt.Fail(&user.ID, "bad_2fa_code")
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid authentication code"})
+	}
+
+	attempt.Succeed(user.ID)
+
+	deviceName, _ := claims["device_name"].(string)
+	return h.completeLogin(c, &user, deviceName)
+}
+
+// throttledResponse tells the client how long to wait before the next login attempt
+func throttledResponse(c *fiber.Ctx, wait time.Duration, locked bool) error {
+	seconds := int(wait.Seconds()) + 1
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+
+	message := "Too many login attempts. Please wait before trying again."
+	if locked {
+		message = "Too many failed login attempts. Login is temporarily locked."
+	}
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error":       message,
+		"retry_after": seconds,
+	})
+}
+
+// completeLogin starts a new session (token family) for this device and responds with the tokens
+func (h *AuthHandler) completeLogin(c *fiber.Ctx, user *models.User, deviceName string) error {
+	session := models.Session{
+		UserID:     user.ID,
+		DeviceName: deviceName,
+		IPAddress:  c.IP(),
+		UserAgent:  c.Get(fiber.HeaderUserAgent),
+		LastUsedAt: time.Now(),
+		ExpiresAt:  time.Now().Add(h.Config.RefreshTokenExpiration),
+	}
+	if session.DeviceName == "" {
+		session.DeviceName = "Unknown device"
+	}
+
+	var accessToken, refreshToken string
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+		var err error
+		accessToken, refreshToken, err = h.issueTokens(tx, user, &session)
+		return err
+	})
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not login"})
 	}
 
 	return c.JSON(fiber.Map{
-		"token": t,
+		"token":         accessToken,
+		"refresh_token": refreshToken,
+		"expires_in":    int(h.Config.JWTExpiration.Seconds()),
 		"user": fiber.Map{
-			"id":        user.ID,
-			"username":  user.Username,
-			"email":     user.Email,
-			"role":      user.Role,
-			"image_url": user.ImageURL,
-			"points":    user.Points,
+			"id":                 user.ID,
+			"username":           user.Username,
+			"email":              user.Email,
+			"role":               user.Role,
+			"image_url":          user.ImageURL,
+			"points":             user.Points,
+			"is_verified":        user.IsVerified,
+			"phone_verified":     user.PhoneVerified,
+			"two_factor_enabled": user.TOTPEnabled,
 		},
 	})
 }
+
+// Refresh - POST /api/auth/refresh
+// Exchanges a refresh token for a new access token and a new refresh token.
+// A refresh token can only be used once; presenting it again revokes the whole session.
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	var req RefreshRequest
+	if err := c.BodyParser(&req); err != nil || req.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Refresh token is required"})
+	}
+
+	var stored models.RefreshToken
+	if err := h.DB.Preload("Session").Where("token_hash = ?", utils.HashToken(req.RefreshToken)).First(&stored).Error; err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid refresh token"})
+	}
+
+	session := stored.Session
+	if !session.IsActive() {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Session has been revoked"})
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Refresh token has expired"})
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, session.UserID).Error; err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+	}
+	if user.IsBanned() {
+		return c.Status(fiber.StatusForbidden).JSON(utils.BannedResponse(&user))
+	}
+
+	var accessToken, refreshToken string
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		// Mark the presented token as used. If another request already used it,
+		// this is a replay and the token family must be killed.
+		now := time.Now()
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", stored.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errRefreshTokenReused
+		}
+
+		if err := tx.Model(&session).Updates(map[string]interface{}{
+			"last_used_at": now,
+			"ip_address":   c.IP(),
+			"user_agent":   c.Get(fiber.HeaderUserAgent),
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		accessToken, refreshToken, err = h.issueTokens(tx, &user, &session)
+		return err
+	})
+
+	if errors.Is(err, errRefreshTokenReused) {
+		h.revokeSession(session.ID, "refresh_token_reuse")
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Refresh token has already been used. Session revoked."})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not refresh token"})
+	}
+
+	return c.JSON(fiber.Map{
+		"token":         accessToken,
+		"refresh_token": refreshToken,
+		"expires_in":    int(h.Config.JWTExpiration.Seconds()),
+	})
+}
+
+// issueTokens creates a new refresh token in the session's chain and signs a matching access token
+func (h *AuthHandler) issueTokens(tx *gorm.DB, user *models.User, session *models.Session) (string, string, error) {
+	refreshToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	stored := models.RefreshToken{
+		SessionID: session.ID,
+		TokenHash: utils.HashToken(refreshToken),
+		ExpiresAt: session.ExpiresAt,
+	}
+	if err := tx.Create(&stored).Error; err != nil {
+		return "", "", err
+	}
+
+	accessToken, err := h.Tokens.IssueAccessToken(user.ID, user.Role, session.ID, h.Config.JWTExpiration)
+	if err != nil {
+		return "", "", err
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// revokeSession revokes a session and kicks its live websocket connections
+func (h *AuthHandler) revokeSession(sessionID uint, reason string) error {
+	if err := utils.RevokeSession(h.DB, sessionID, reason); err != nil {
+		return err
+	}
+	h.Hub.DisconnectSession(sessionID, reason)
+	return nil
+}
+
+// Logout - POST /api/auth/logout
+// Revokes the session of the token used for this request
+func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	sessionID := c.Locals("session_id").(uint)
+
+	if err := h.revokeSession(sessionID, "logout"); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not logout"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Logged out successfully"})
+}
+
+// LogoutAll - POST /api/auth/logout-all
+// Revokes every session of the current user, including this one
+func (h *AuthHandler) LogoutAll(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	if err := utils.RevokeUserSessions(h.DB, userID, 0, "logout_all"); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not logout"})
+	}
+	h.Hub.DisconnectUser(userID, "logout_all")
+
+	return c.JSON(fiber.Map{"message": "Logged out from all devices"})
+}
+
+// GetSessions - GET /api/auth/sessions
+// Lists the active devices of the current user
+func (h *AuthHandler) GetSessions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	currentSessionID := c.Locals("session_id").(uint)
+
+	var sessions []models.Session
+	if err := h.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at desc").
+		Find(&sessions).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch sessions"})
+	}
+
+	type SessionResult struct {
+		ID         uint      `json:"id"`
+		DeviceName string    `json:"device_name"`
+		IPAddress  string    `json:"ip_address"`
+		UserAgent  string    `json:"user_agent"`
+		LastUsedAt time.Time `json:"last_used_at"`
+		CreatedAt  time.Time `json:"created_at"`
+		Current    bool      `json:"current"`
+	}
+
+	results := make([]SessionResult, 0, len(sessions))
+	for _, s := range sessions {
+		results = append(results, SessionResult{
+			ID:         s.ID,
+			DeviceName: s.DeviceName,
+			IPAddress:  s.IPAddress,
+			UserAgent:  s.UserAgent,
+			LastUsedAt: s.LastUsedAt,
+			CreatedAt:  s.CreatedAt,
+			Current:    s.ID == currentSessionID,
+		})
+	}
+
+	return c.JSON(fiber.Map{"data": results})
+}
+
+// RevokeSession - DELETE /api/auth/sessions/:id
+// Logs out a single device of the current user
+func (h *AuthHandler) RevokeSession(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	sessionID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid session ID"})
+	}
+
+	var session models.Session
+	if err := h.DB.Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Session not found"})
+	}
+
+	if err := h.revokeSession(session.ID, "revoked_by_user"); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not revoke session"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Session revoked"})
+}
+
+// ForgotPassword - POST /api/auth/forgot-password
+// Always answers the same way so the endpoint cannot be used to discover registered emails.
+func (h *AuthHandler) ForgotPassword(c *fiber.Ctx) error {
+	var req ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil || req.Email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Email is required"})
+	}
+
+	response := fiber.Map{"message": "If an account with that email exists, a password reset link has been sent"}
+
+	var user models.User
+	if err := h.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		return c.JSON(response)
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		log.Printf("Failed to generate reset token for user %d: %v", user.ID, err)
+		return c.JSON(response)
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		// Only the most recent link stays valid
+		if err := tx.Model(&models.PasswordReset{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.PasswordReset{
+			UserID:    user.ID,
+			TokenHash: utils.HashToken(token),
+			ExpiresAt: time.Now().Add(h.Config.PasswordResetExpiration),
+			IPAddress: c.IP(),
+		}).Error
+	})
+	if err != nil {
+		log.Printf("Failed to store reset token for user %d: %v", user.ID, err)
+		return c.JSON(response)
+	}
+
+	// Send in the background so the response time does not depend on whether the email exists
+	link := fmt.Sprintf("%s/reset-password?token=%s", h.Config.AppURL, token)
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone requested a password reset for your account. Use the link below to choose a new password:\n\n%s\n\nThis link expires in %s. If you did not request this, you can ignore this email.\n",
+			user.Username, link, h.Config.PasswordResetExpiration),
+	}
+	go func() {
+		if err := h.Mailer.Send(msg); err != nil {
+			log.Printf("Failed to send reset email to user %d: %v", user.ID, err)
+		}
+	}()
+
+	return c.JSON(response)
+}
+
+// ResetPassword - POST /api/auth/reset-password
+// Sets a new password using a reset token and logs out every device
+func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var req ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil || req.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	if len(req.NewPassword) < minPasswordLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("Password must be at least %d characters", minPasswordLength)})
+	}
+
+	var reset models.PasswordReset
+	if err := h.DB.Where("token_hash = ?", utils.HashToken(req.Token)).First(&reset).Error; err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Reset link is invalid or has expired"})
+	}
+	if reset.UsedAt != nil || time.Now().After(reset.ExpiresAt) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Reset link is invalid or has expired"})
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not hash password"})
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		// Consume the token; a concurrent request using the same token loses
+		result := tx.Model(&models.PasswordReset{}).
+			Where("id = ? AND used_at IS NULL", reset.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if err := tx.Model(&models.User{}).Where("id = ?", reset.UserID).Update("password", hashedPassword).Error; err != nil {
+			return err
+		}
+		return utils.RevokeUserSessions(tx, reset.UserID, 0, "password_reset")
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Reset link is invalid or has expired"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not reset password"})
+	}
+
+	h.Hub.DisconnectUser(reset.UserID, "password_reset")
+
+	return c.JSON(fiber.Map{"message": "Password has been reset. Please login again."})
+}
+
+// ChangePassword - POST /api/auth/change-password
+// Requires the current password and logs out every other device
+func (h *AuthHandler) ChangePassword(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	sessionID := c.Locals("session_id").(uint)
+
+	var req ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	if len(req.NewPassword) < minPasswordLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("Password must be at least %d characters", minPasswordLength)})
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if !utils.CheckPasswordHash(req.OldPassword, user.Password) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Current password is incorrect"})
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not hash password"})
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("password", hashedPassword).Error; err != nil {
+			return err
+		}
+		return utils.RevokeUserSessions(tx, userID, sessionID, "password_changed")
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not change password"})
+	}
+
+	h.Hub.DisconnectOtherSessions(userID, sessionID, "password_changed")
+
+	return c.JSON(fiber.Map{"message": "Password changed. Other devices have been logged out."})
+}
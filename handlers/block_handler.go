@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"meetup_backend/internal/ws"
+	"meetup_backend/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BlockHandler struct {
+	DB  *gorm.DB
+	Hub *ws.Hub
+}
+
+func NewBlockHandler(db *gorm.DB, hub *ws.Hub) *BlockHandler {
+	return &BlockHandler{DB: db, Hub: hub}
+}
+
+// BlockedUser is an entry in the blocked users list
+type BlockedUser struct {
+	ID        uint      `json:"id"`
+	Username  string    `json:"username"`
+	FullName  string    `json:"full_name"`
+	ImageURL  string    `json:"image_url"`
+	BlockedAt time.Time `json:"blocked_at"`
+}
+
+// BlockUser - POST /api/users/:id/block
+// Also removes follows in both directions
+func (h *BlockHandler) BlockUser(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	targetID, err := c.ParamsInt("id")
+	if err != nil || targetID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	if uint(targetID) == userID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot block yourself"})
+	}
+
+	var target models.User
+	if err := h.DB.Select("id").First(&target, targetID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		block := models.Block{BlockerID: userID, BlockedID: target.ID}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&block).Error; err != nil {
+			return err
+		}
+		return tx.Where("(follower_id = ? AND following_id = ?) OR (follower_id = ? AND following_id = ?)",
+			userID, target.ID, target.ID, userID).Delete(&models.Follow{}).Error
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not block user"})
+	}
+
+	// Presence is no longer shared, so make both sides see each other as offline
+	h.Hub.SendToUser(target.ID, offlineStatus(userID))
+	h.Hub.SendToUser(userID, offlineStatus(target.ID))
+
+	return c.JSON(fiber.Map{"message": "User blocked"})
+}
+
+// UnblockUser - DELETE /api/users/:id/block
+func (h *BlockHandler) UnblockUser(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	targetID, err := c.ParamsInt("id")
+	if err != nil || targetID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	result := h.DB.Where("blocker_id = ? AND blocked_id = ?", userID, targetID).Delete(&models.Block{})
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not unblock user"})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User is not blocked"})
+	}
+
+	return c.JSON(fiber.Map{"message": "User unblocked"})
+}
+
+// GetBlockedUsers - GET /api/users/me/blocked
+func (h *BlockHandler) GetBlockedUsers(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	users := []BlockedUser{}
+	err := h.DB.Table("blocks").
+		Select("users.id, users.username, users.full_name, users.image_url, blocks.created_at AS blocked_at").
+		Joins("JOIN users ON users.id = blocks.blocked_id").
+		Where("blocks.blocker_id = ?", userID).
+		Order("blocks.id desc").
+		Scan(&users).Error
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch blocked users"})
+	}
+
+	return c.JSON(fiber.Map{"data": users})
+}
+
+func offlineStatus(userID uint) []byte {
+	statusJSON, _ := json.Marshal(map[string]interface{}{
+		"type":      "user_status",
+		"user_id":   userID,
+		"is_online": false,
+	})
+	return statusJSON
+}
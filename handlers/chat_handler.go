@@ -2,28 +2,52 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"meetup_backend/internal/points"
 	"meetup_backend/internal/ws"
 	"meetup_backend/models"
+	"meetup_backend/utils"
 	"time"
 
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ChatHandler struct {
-	Hub *ws.Hub
-	DB  *gorm.DB
+	Hub     *ws.Hub
+	DB      *gorm.DB
+	Tickets *ws.TicketStore
 }
 
-func NewChatHandler(hub *ws.Hub, db *gorm.DB) *ChatHandler {
+func NewChatHandler(hub *ws.Hub, db *gorm.DB, tickets *ws.TicketStore) *ChatHandler {
 	return &ChatHandler{
-		Hub: hub,
-		DB:  db,
+		Hub:     hub,
+		DB:      db,
+		Tickets: tickets,
 	}
 }
 
+// CreateTicket - POST /api/ws/ticket
+// Issues a single-use ticket for opening the websocket: ws://host/ws?ticket=<ticket>
+func (h *ChatHandler) CreateTicket(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	sessionID := c.Locals("session_id").(uint)
+
+	ticket, expiresAt, err := h.Tickets.Issue(userID, sessionID, c.IP())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create ticket"})
+	}
+
+	return c.JSON(fiber.Map{
+		"ticket":     ticket,
+		"expires_at": expiresAt,
+	})
+}
+
 // WebSocketUpgradeMiddleware ensures the client is trying to upgrade to WebSocket
 func (h *ChatHandler) WebSocketUpgradeMiddleware(c *fiber.Ctx) error {
 	if websocket.IsWebSocketUpgrade(c) {
@@ -33,7 +57,9 @@ func (h *ChatHandler) WebSocketUpgradeMiddleware(c *fiber.Ctx) error {
 	return fiber.ErrUpgradeRequired
 }
 
-// Handler returns the websocket handler function
+// Handler returns the websocket handler function.
+// "access_token" is accepted as subprotocol for clients that authenticate via
+// the Sec-WebSocket-Protocol header instead of a ticket.
 func (h *ChatHandler) Handler() fiber.Handler {
 	return websocket.New(func(c *websocket.Conn) {
 		// Retrieve user_id from Locals (set by main.go middleware)
@@ -44,14 +70,10 @@ func (h *ChatHandler) Handler() fiber.Handler {
 			return
 		}
 
+		sessionID, _ := c.Locals("session_id").(uint)
+
 		// Create Client
-		client := &ws.Client{
-			Hub:    h.Hub,
-			Conn:   c,
-			Send:   make(chan []byte, 256),
-			UserID: uint(userID),
-			DB:     h.DB, // Pass DB connection
-		}
+		client := ws.NewClient(h.Hub, c, userID, sessionID, h.DB)
 
 		// Register to Hub (this will trigger limitRegister which sends unread messages)
 		client.Hub.Register <- client
@@ -59,6 +81,8 @@ func (h *ChatHandler) Handler() fiber.Handler {
 		// Start Pumps
 		go client.WritePump()
 		client.ReadPump()
+	}, websocket.Config{
+		Subprotocols: []string{"access_token"},
 	})
 }
 
@@ -79,6 +103,29 @@ func (h *ChatHandler) InitPrivateChat(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot chat with yourself"})
 	}
 
+	var target models.User
+	if err := h.DB.Select("id", "require_verified_phone").First(&target, req.TargetUserID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if utils.IsBlockedBetween(h.DB, userID, target.ID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "You cannot chat with this user",
+			"code":  "blocked",
+		})
+	}
+
+	// Sellers can refuse buyers that have not verified a phone number
+	if target.RequireVerifiedPhone {
+		var me models.User
+		if err := h.DB.Select("id", "phone_verified").First(&me, userID).Error; err != nil || !me.PhoneVerified {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "This seller only chats with users who have a verified phone number",
+				"code":  "phone_verification_required",
+			})
+		}
+	}
+
 	// 1. Check if room exists
 	// Query is complex: Find a room where both users are participants
 	// Simplified approach: Find all private rooms for User A, then filter for User B
@@ -175,6 +222,7 @@ func (h *ChatHandler) GetMyChats(c *fiber.Ctx) error {
 				SELECT COUNT(*) 
 				FROM messages m 
 				WHERE m.chat_room_id = cr.id 
+				AND m.deleted_at IS NULL
 				AND m.is_read = false 
 				AND m.sender_id != ?
 			) as unread_count
@@ -201,13 +249,14 @@ func (h *ChatHandler) GetChatMessages(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid room ID"})
 	}
 
-	// 1. Verify User is Participant
+	// 1. Verify User is Participant (moderators may read any room)
 	var count int64
 	h.DB.Model(&models.ChatParticipant{}).
 		Where("chat_room_id = ? AND user_id = ?", roomID, userID).
 		Count(&count)
 
-	if count == 0 {
+	isModerator := count == 0 && utils.Can(c, utils.PermModerateChat)
+	if count == 0 && !isModerator {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You are not a member of this chat room"})
 	}
 
@@ -217,7 +266,7 @@ func (h *ChatHandler) GetChatMessages(c *fiber.Ctx) error {
 	limit := c.QueryInt("limit", 50)
 	offset := c.QueryInt("offset", 0)
 
-	if err := h.DB.Preload("Sender").
+	if err := h.DB.Preload("Sender", models.PublicSender).
 		Where("chat_room_id = ?", roomID).
 		Order("created_at DESC"). // Newest first
 		Limit(limit).
@@ -229,20 +278,21 @@ func (h *ChatHandler) GetChatMessages(c *fiber.Ctx) error {
 	// Reverse to Oldest First for Chat UI usually, or keep Newest First and Client reverses
 	// Let's keep Newest First (Desc) as it's standard for pagination, Client should handle display order.
 
-	// Delete retrieved messages to save resources as requested (Ephemeral-like)
+	// Delete retrieved messages (Ephemeral-like); they stay reportable until purged
 	// We only delete messages that strictly match the fetch criteria to avoid deleting unread ones if logic differs,
 	// but here we just delete what we found.
-	if len(messages) > 0 {
+	// A moderator reviewing the room must not consume the messages for the participants.
+	if len(messages) > 0 && !isModerator {
 		var messageIDs []uint
 		for _, m := range messages {
 			messageIDs = append(messageIDs, m.ID)
 		}
-		// Hard delete to save space
-		if err := h.DB.Unscoped().Delete(&models.Message{}, messageIDs).Error; err != nil {
+		// Soft delete: hidden from the chat, kept for reports until the message purger removes it
+		if err := h.DB.Delete(&models.Message{}, messageIDs).Error; err != nil {
 			log.Printf("Failed to delete fetched messages: %v", err)
 			// Non-blocking error
 		} else {
-			log.Printf("Deleted %d fetched messages from DB to save resources", len(messages))
+			log.Printf("Hid %d fetched messages from the chat", len(messages))
 		}
 	}
 
@@ -259,13 +309,13 @@ func (h *ChatHandler) GetRoomStatus(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid room ID"})
 	}
 
-	// 1. Verify User is Participant
+	// 1. Verify User is Participant (moderators may inspect any room)
 	var count int64
 	h.DB.Model(&models.ChatParticipant{}).
 		Where("chat_room_id = ? AND user_id = ?", roomID, userID).
 		Count(&count)
 
-	if count == 0 {
+	if count == 0 && !utils.Can(c, utils.PermModerateChat) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You are not a member of this chat room"})
 	}
 
@@ -324,6 +374,10 @@ func (h *ChatHandler) DeleteChat(c *fiber.Ctx) error {
 	// Check if user is participant
 	var participant models.ChatParticipant
 	if err := h.DB.Where("chat_room_id = ? AND user_id = ?", roomID, userID).First(&participant).Error; err != nil {
+		// Moderators may remove a room they are not part of (e.g. spam). This deletes it for everyone.
+		if utils.Can(c, utils.PermModerateChat) {
+			return h.deleteRoomAsModerator(c, uint(roomID))
+		}
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Chat not found or not a participant"})
 	}
 
@@ -341,6 +395,30 @@ func (h *ChatHandler) DeleteChat(c *fiber.Ctx) error {
 	})
 }
 
+// deleteRoomAsModerator soft-deletes a room together with all its participant entries
+func (h *ChatHandler) deleteRoomAsModerator(c *fiber.Ctx, roomID uint) error {
+	var room models.ChatRoom
+	if err := h.DB.First(&room, roomID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Chat not found"})
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("chat_room_id = ?", roomID).Delete(&models.ChatParticipant{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&room).Error
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete chat"})
+	}
+
+	log.Printf("Chat room %d deleted by moderator %d", roomID, c.Locals("user_id").(uint))
+
+	return c.JSON(fiber.Map{
+		"message": "Chat deleted successfully",
+	})
+}
+
 // ToggleMeetupReadyRequest defines payload for toggling ready state
 type ToggleMeetupReadyRequest struct {
 	RoomID uint `json:"room_id"`
@@ -360,8 +438,8 @@ func (h *ChatHandler) ToggleMeetupReady(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
 	}
 
-	if user.Points < 5 {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient points. You need 5 points."})
+	if user.Points < points.MeetupFee {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": fmt.Sprintf("Insufficient points. You need %d points.", points.MeetupFee)})
 	}
 
 	// 2. Get Chat Room
@@ -382,7 +460,15 @@ func (h *ChatHandler) ToggleMeetupReady(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Not a participant"})
 	}
 
-	// 3. Toggle Ready State
+	// 3. Toggle Ready State. The room row stays locked until the new state is
+	// stored, so concurrent toggles (e.g. a double-click) see each other's result
+	// and only one of them can confirm the meetup.
+	tx := h.DB.Begin()
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "meetup_ready_user_ids").First(&room, room.ID).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update room state"})
+	}
+
 	var readyUserIDs []uint
 	if room.MeetupReadyUserIDs != "" {
 		if err := json.Unmarshal([]byte(room.MeetupReadyUserIDs), &readyUserIDs); err != nil {
@@ -407,17 +493,20 @@ func (h *ChatHandler) ToggleMeetupReady(c *fiber.Ctx) error {
 		readyUserIDs = append(readyUserIDs, userID)
 	}
 
+	// 4. Check if MUTUAL AGREEMENT (Both users ready)
+	// Private chat has exactly 2 participants. The agreement stores the reset
+	// state in the same transaction, so the new list is not saved first.
+	if len(readyUserIDs) >= 2 {
+		return h.handleMeetupAgreement(c, tx, &room, readyUserIDs)
+	}
+
 	// Save new state
-	newStateJSON, _ := json.Marshal(readyUserIDs)
-	room.MeetupReadyUserIDs = string(newStateJSON)
-	if err := h.DB.Save(&room).Error; err != nil {
+	if err := saveReadyState(tx, &room, readyUserIDs); err != nil {
+		tx.Rollback()
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update room state"})
 	}
-
-	// 4. Check if MUTUAL AGREEMENT (Both users ready)
-	// Private chat has exactly 2 participants
-	if len(readyUserIDs) >= 2 {
-		return h.handleMeetupAgreement(c, &room, readyUserIDs)
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update room state"})
 	}
 
 	// 5. Broadcast Status Update (One user ready, or user cancelled)
@@ -430,39 +519,105 @@ func (h *ChatHandler) ToggleMeetupReady(c *fiber.Ctx) error {
 	})
 }
 
-// handleMeetupAgreement executes the point deduction and confirmation
-func (h *ChatHandler) handleMeetupAgreement(c *fiber.Ctx, room *models.ChatRoom, readyUserIDs []uint) error {
-	tx := h.DB.Begin()
-
+// handleMeetupAgreement executes the point deduction and confirmation inside
+// tx, which holds the room lock, and commits or rolls it back
+func (h *ChatHandler) handleMeetupAgreement(c *fiber.Ctx, tx *gorm.DB, room *models.ChatRoom, readyUserIDs []uint) error {
 	// Deterime cost
-	cost := 5
+	cost := points.MeetupFee
+
+	// Charges are undone up to here when someone cannot pay
+	if err := tx.SavePoint("meetup_agreement").Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to record meetup"})
+	}
+
+	// Record the meetup, participants can review each other afterwards
+	meetup := models.Meetup{ChatRoomID: room.ID, PointsCost: cost, ConfirmedAt: time.Now()}
+	for _, uid := range readyUserIDs {
+		meetup.Participants = append(meetup.Participants, models.MeetupParticipant{UserID: uid})
+	}
+	if err := tx.Create(&meetup).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to record meetup"})
+	}
 
 	// Deduct points from ALL ready users (should be 2)
 	for _, uid := range readyUserIDs {
-		if err := tx.Model(&models.User{}).Where("id = ?", uid).Update("points", gorm.Expr("points - ?", cost)).Error; err != nil {
+		_, err := points.Apply(tx, points.Change{
+			UserID:         uid,
+			Delta:          -cost,
+			Reason:         points.ReasonMeetupFee,
+			ChatRoomID:     &room.ID,
+			MeetupID:       &meetup.ID,
+			IdempotencyKey: fmt.Sprintf("meetup:%d:fee:%d", meetup.ID, uid),
+		})
+		if errors.Is(err, points.ErrInsufficientPoints) {
+			// They can no longer pay, so they are not ready anymore; the others stay ready
+			return h.dropReadyUser(c, tx, room, readyUserIDs, uid)
+		}
+		if err != nil {
 			tx.Rollback()
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to deduct points"})
 		}
 	}
 
 	// Reset Ready State
-	room.MeetupReadyUserIDs = "[]" // Reset to empty array
-	if err := tx.Save(room).Error; err != nil {
+	if err := saveReadyState(tx, room, []uint{}); err != nil {
 		tx.Rollback()
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reset room"})
 	}
 
-	tx.Commit()
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to confirm meetup"})
+	}
 
 	// Broadcast CONFIRMATION
-	h.broadcastMeetupConfirmed(room.ID)
+	h.broadcastMeetupConfirmed(room.ID, meetup.ID)
 
 	return c.JSON(fiber.Map{
 		"message":   "Meetup confirmed! Points deducted.",
 		"confirmed": true,
+		"meetup_id": meetup.ID,
+	})
+}
+
+// dropReadyUser undoes the meetup and its charges, stores the ready list
+// without userID and tells the room
+func (h *ChatHandler) dropReadyUser(c *fiber.Ctx, tx *gorm.DB, room *models.ChatRoom, readyUserIDs []uint, userID uint) error {
+	remaining := make([]uint, 0, len(readyUserIDs))
+	for _, uid := range readyUserIDs {
+		if uid != userID {
+			remaining = append(remaining, uid)
+		}
+	}
+
+	if err := tx.RollbackTo("meetup_agreement").Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update room state"})
+	}
+	if err := saveReadyState(tx, room, remaining); err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update room state"})
+	}
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update room state"})
+	}
+
+	h.broadcastMeetupUpdate(room.ID, remaining)
+
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"error":   fmt.Sprintf("A participant no longer has the %d points needed", points.MeetupFee),
+		"user_id": userID,
 	})
 }
 
+// saveReadyState stores the list of users ready to meet
+func saveReadyState(tx *gorm.DB, room *models.ChatRoom, readyUserIDs []uint) error {
+	stateJSON, _ := json.Marshal(readyUserIDs)
+	room.MeetupReadyUserIDs = string(stateJSON)
+	return tx.Model(room).Update("meetup_ready_user_ids", room.MeetupReadyUserIDs).Error
+}
+
 // broadcastMeetupUpdate notifies room participants of current ready status
 func (h *ChatHandler) broadcastMeetupUpdate(roomID uint, readyUserIDs []uint) {
 	msgJSON, _ := json.Marshal(map[string]interface{}{
@@ -487,10 +642,11 @@ func (h *ChatHandler) broadcastMeetupUpdate(roomID uint, readyUserIDs []uint) {
 }
 
 // broadcastMeetupConfirmed notifies room that meetup is ON
-func (h *ChatHandler) broadcastMeetupConfirmed(roomID uint) {
+func (h *ChatHandler) broadcastMeetupConfirmed(roomID, meetupID uint) {
 	msgJSON, _ := json.Marshal(map[string]interface{}{
 		"type":         "meetup_confirmed",
 		"chat_room_id": roomID,
+		"meetup_id":    meetupID,
 	})
 
 	var room models.ChatRoom
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+
+	"meetup_backend/internal/points"
+	"meetup_backend/internal/ws"
+	"meetup_backend/models"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// newMeetupTestRoom returns a private room between two users with enough points for a meetup
+func newMeetupTestRoom(t *testing.T) (*ChatHandler, *gorm.DB, models.ChatRoom, [2]models.User) {
+	t.Helper()
+
+	db := newTestDB(t, &models.User{}, &models.ChatRoom{}, &models.ChatParticipant{}, &models.Meetup{}, &models.MeetupParticipant{}, &models.PointTransaction{})
+
+	users := [2]models.User{
+		{Username: "alice", Email: "alice@example.com", Points: 10},
+		{Username: "bob", Email: "bob@example.com", Points: 10},
+	}
+	for i := range users {
+		if err := db.Create(&users[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	room := models.ChatRoom{Participants: []models.ChatParticipant{{UserID: users[0].ID}, {UserID: users[1].ID}}}
+	if err := db.Create(&room).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	hub := ws.NewHub()
+	go hub.Run()
+	return NewChatHandler(hub, db, nil), db, room, users
+}
+
+func toggleReady(t *testing.T, h *ChatHandler, roomID, userID uint) (int, map[string]interface{}) {
+	t.Helper()
+
+	app := fiber.New()
+	app.Post("/toggle-ready", asUser(userID), h.ToggleMeetupReady)
+	return doJSON(t, app, "POST", "/toggle-ready", ToggleMeetupReadyRequest{RoomID: roomID})
+}
+
+func TestToggleMeetupReadyChargesOnce(t *testing.T) {
+	h, db, room, users := newMeetupTestRoom(t)
+
+	if status, body := toggleReady(t, h, room.ID, users[0].ID); status != fiber.StatusOK || body["confirmed"] != false {
+		t.Fatalf("first toggle returned %d: %v", status, body)
+	}
+	if status, body := toggleReady(t, h, room.ID, users[1].ID); status != fiber.StatusOK || body["confirmed"] != true {
+		t.Fatalf("second toggle returned %d: %v", status, body)
+	}
+
+	// A repeated click after the confirmation starts over instead of confirming again
+	if status, body := toggleReady(t, h, room.ID, users[1].ID); status != fiber.StatusOK || body["confirmed"] != false {
+		t.Fatalf("repeated toggle returned %d: %v", status, body)
+	}
+
+	var meetups int64
+	db.Model(&models.Meetup{}).Where("chat_room_id = ?", room.ID).Count(&meetups)
+	if meetups != 1 {
+		t.Fatalf("%d meetups recorded, want 1", meetups)
+	}
+	for _, u := range users {
+		var user models.User
+		db.First(&user, u.ID)
+		if user.Points != 10-points.MeetupFee {
+			t.Fatalf("user %d has %d points, want %d", u.ID, user.Points, 10-points.MeetupFee)
+		}
+	}
+}
+
+func TestToggleMeetupReadyDropsUserWhoCannotPay(t *testing.T) {
+	h, db, room, users := newMeetupTestRoom(t)
+
+	if status, body := toggleReady(t, h, room.ID, users[0].ID); status != fiber.StatusOK {
+		t.Fatalf("first toggle returned %d: %v", status, body)
+	}
+	// Alice spent her points elsewhere after saying she was ready
+	db.Model(&users[0]).Update("points", points.MeetupFee-1)
+
+	if status, body := toggleReady(t, h, room.ID, users[1].ID); status != fiber.StatusForbidden {
+		t.Fatalf("toggle returned %d: %v", status, body)
+	}
+
+	var meetups int64
+	db.Model(&models.Meetup{}).Count(&meetups)
+	if meetups != 0 {
+		t.Fatalf("%d meetups recorded, want 0", meetups)
+	}
+
+	// Bob's charge was undone and he is still waiting for Alice
+	var bob models.User
+	db.First(&bob, users[1].ID)
+	if bob.Points != 10 {
+		t.Fatalf("bob has %d points, want 10", bob.Points)
+	}
+	var entries int64
+	db.Model(&models.PointTransaction{}).Count(&entries)
+	if entries != 0 {
+		t.Fatalf("%d ledger entries written, want 0", entries)
+	}
+	db.First(&room, room.ID)
+	if want := fmt.Sprintf("[%d]", bob.ID); room.MeetupReadyUserIDs != want {
+		t.Fatalf("ready list is %s, want %s", room.MeetupReadyUserIDs, want)
+	}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+
+	"meetup_backend/internal/ws"
+	"meetup_backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestGetChatMessagesShowsOnlyPublicSender(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.ChatRoom{}, &models.ChatParticipant{}, &models.Message{})
+
+	phone := "+6281234567890"
+	seller := models.User{
+		Username:          "seller",
+		Email:             "seller@example.com",
+		FullName:          "Seller",
+		Phone:             &phone,
+		PhoneVerified:     true,
+		Latitude:          -6.2,
+		Longitude:         106.8,
+		HideExactLocation: true,
+		WarningCount:      2,
+	}
+	buyer := models.User{Username: "buyer", Email: "buyer@example.com"}
+	db.Create(&seller)
+	db.Create(&buyer)
+	room := models.ChatRoom{Participants: []models.ChatParticipant{{UserID: seller.ID}, {UserID: buyer.ID}}}
+	db.Create(&room)
+	db.Create(&models.Message{ChatRoomID: room.ID, SenderID: seller.ID, Content: "hi"})
+
+	h := NewChatHandler(ws.NewHub(), db, nil)
+	app := fiber.New()
+	app.Get("/chats/:roomID/messages", asUser(buyer.ID), h.GetChatMessages)
+
+	status, body := doJSON(t, app, "GET", fmt.Sprintf("/chats/%d/messages", room.ID), nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("got %d: %v", status, body)
+	}
+	messages, _ := body["messages"].([]interface{})
+	if len(messages) != 1 {
+		t.Fatalf("got %d messages, want 1", len(messages))
+	}
+	sender, _ := messages[0].(map[string]interface{})["sender"].(map[string]interface{})
+
+	want := map[string]interface{}{
+		"id":        float64(seller.ID),
+		"username":  "seller",
+		"full_name": "Seller",
+		"image_url": "",
+	}
+	if len(sender) != len(want) {
+		t.Fatalf("sender has fields %v, want only %v", sender, want)
+	}
+	for key, value := range want {
+		if sender[key] != value {
+			t.Fatalf("sender[%q] = %v, want %v", key, sender[key], value)
+		}
+	}
+}
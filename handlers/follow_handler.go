@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"errors"
+	"meetup_backend/models"
+	"meetup_backend/utils"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type FollowHandler struct {
+	DB *gorm.DB
+}
+
+func NewFollowHandler(db *gorm.DB) *FollowHandler {
+	return &FollowHandler{DB: db}
+}
+
+// FollowUser is an entry in a followers/following list
+type FollowUser struct {
+	ID         uint      `json:"id"`
+	Username   string    `json:"username"`
+	FullName   string    `json:"full_name"`
+	ImageURL   string    `json:"image_url"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 50
+)
+
+// pageParams reads ?cursor=&limit= for lists ordered by descending ID.
+// The cursor is the last ID of the previous page (0 = first page).
+func pageParams(c *fiber.Ctx) (cursor uint, limit int, err error) {
+	if raw := c.Query("cursor"); raw != "" {
+		value, parseErr := strconv.ParseUint(raw, 10, 64)
+		if parseErr != nil {
+			return 0, 0, errors.New("Invalid cursor")
+		}
+		cursor = uint(value)
+	}
+
+	limit = c.QueryInt("limit", defaultPageSize)
+	if limit <= 0 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+	return cursor, limit, nil
+}
+
+// Follow - POST /api/users/:id/follow
+func (h *FollowHandler) Follow(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	targetID, err := c.ParamsInt("id")
+	if err != nil || targetID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	if uint(targetID) == userID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot follow yourself"})
+	}
+
+	var target models.User
+	if err := h.DB.Select("id").First(&target, targetID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if utils.IsBlockedBetween(h.DB, userID, target.ID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You cannot follow this user"})
+	}
+
+	// Following twice is not an error
+	follow := models.Follow{FollowerID: userID, FollowingID: target.ID}
+	if err := h.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&follow).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not follow user"})
+	}
+
+	return c.JSON(fiber.Map{"message": "User followed"})
+}
+
+// Unfollow - DELETE /api/users/:id/follow
+func (h *FollowHandler) Unfollow(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	targetID, err := c.ParamsInt("id")
+	if err != nil || targetID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	result := h.DB.Where("follower_id = ? AND following_id = ?", userID, targetID).Delete(&models.Follow{})
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not unfollow user"})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "You are not following this user"})
+	}
+
+	return c.JSON(fiber.Map{"message": "User unfollowed"})
+}
+
+// GetFollowers - GET /api/users/:id/followers (Public)
+func (h *FollowHandler) GetFollowers(c *fiber.Ctx) error {
+	return h.listFollows(c, "following_id", "follower_id")
+}
+
+// GetFollowing - GET /api/users/:id/following (Public)
+func (h *FollowHandler) GetFollowing(c *fiber.Ctx) error {
+	return h.listFollows(c, "follower_id", "following_id")
+}
+
+// listFollows pages through follows where matchColumn is the user in the URL,
+// returning the users in userColumn
+func (h *FollowHandler) listFollows(c *fiber.Ctx, matchColumn, userColumn string) error {
+	targetID, err := c.ParamsInt("id")
+	if err != nil || targetID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	cursor, limit, err := pageParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var rows []struct {
+		FollowID uint
+		FollowUser
+	}
+	query := h.DB.Table("follows").
+		Select("follows.id AS follow_id, users.id, users.username, users.full_name, users.image_url, follows.created_at AS followed_at").
+		Joins("JOIN users ON users.id = follows."+userColumn+" AND users.deleted_at IS NULL").
+		Where("follows."+matchColumn+" = ?", targetID).
+		Order("follows.id desc").
+		Limit(limit)
+	if cursor != 0 {
+		query = query.Where("follows.id < ?", cursor)
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch users"})
+	}
+
+	users := make([]FollowUser, 0, len(rows))
+	var nextCursor *uint
+	for i := range rows {
+		users = append(users, rows[i].FollowUser)
+	}
+	if len(rows) == limit {
+		nextCursor = &rows[len(rows)-1].FollowID
+	}
+
+	return c.JSON(fiber.Map{"data": users, "next_cursor": nextCursor})
+}
+
+// GetFeed - GET /api/feed?cursor=&limit=
+// Available products from followed sellers, newest first
+func (h *FollowHandler) GetFeed(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	cursor, limit, err := pageParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	following := h.DB.Model(&models.Follow{}).Select("following_id").Where("follower_id = ?", userID)
+	query := h.DB.Preload("Seller", func(db *gorm.DB) *gorm.DB {
+		return db.Select(sellerColumns)
+	}).
+		Where("seller_id IN (?) AND status = ? AND hidden_at IS NULL", following, "available").
+		Order("id desc").
+		Limit(limit)
+	if cursor != 0 {
+		query = query.Where("id < ?", cursor)
+	}
+
+	var products []models.Product
+	if err := query.Find(&products).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch feed"})
+	}
+
+	for i := range products {
+		presentLocation(&products[i], userID)
+	}
+
+	var nextCursor *uint
+	if len(products) == limit {
+		nextCursor = &products[len(products)-1].ID
+	}
+
+	return c.JSON(fiber.Map{"data": products, "next_cursor": nextCursor})
+}
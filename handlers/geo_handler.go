@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"math"
+	"meetup_backend/internal/geo"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type GeoHandler struct {
+	Geo *geo.Gazetteer
+}
+
+func NewGeoHandler(gazetteer *geo.Gazetteer) *GeoHandler {
+	return &GeoHandler{Geo: gazetteer}
+}
+
+const (
+	defaultAreaResults = 10
+	maxAreaResults     = 50
+)
+
+// SearchAreas - GET /api/geo/areas?q=&level=
+// Autocomplete for provinces, cities and districts from the gazetteer
+func (h *GeoHandler) SearchAreas(c *fiber.Ctx) error {
+	query := c.Query("q")
+	if query == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Query parameter 'q' is required"})
+	}
+
+	level := c.Query("level")
+	switch level {
+	case "", geo.LevelProvince, geo.LevelCity, geo.LevelDistrict:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "level must be one of province, city, district"})
+	}
+
+	limit := c.QueryInt("limit", defaultAreaResults)
+	if limit <= 0 || limit > maxAreaResults {
+		limit = defaultAreaResults
+	}
+
+	areas := h.Geo.Search(query, level, limit)
+	if areas == nil {
+		areas = []geo.Area{}
+	}
+	return c.JSON(fiber.Map{"data": areas})
+}
+
+// ReverseGeocode - GET /api/geo/reverse?lat=&lng=
+func (h *GeoHandler) ReverseGeocode(c *fiber.Ctx) error {
+	lat, latErr := strconv.ParseFloat(c.Query("lat"), 64)
+	lng, lngErr := strconv.ParseFloat(c.Query("lng"), 64)
+	if latErr != nil || lngErr != nil || math.IsNaN(lat) || math.IsNaN(lng) ||
+		lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Query parameters 'lat' and 'lng' must be valid coordinates"})
+	}
+
+	place, ok := h.Geo.Reverse(lat, lng)
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No area found for this location"})
+	}
+	return c.JSON(fiber.Map{"data": place})
+}
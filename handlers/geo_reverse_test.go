@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestReverseGeocodeRejectsNaN(t *testing.T) {
+	app := fiber.New()
+	app.Get("/reverse", NewGeoHandler(nil).ReverseGeocode)
+
+	for _, query := range []string{"lat=NaN&lng=106.8", "lat=-6.2&lng=nan"} {
+		if status, body := doJSON(t, app, "GET", "/reverse?"+query, nil); status != fiber.StatusBadRequest {
+			t.Fatalf("%s returned %d: %v", query, status, body)
+		}
+	}
+}
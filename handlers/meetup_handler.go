@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"errors"
+	"meetup_backend/config"
+	"meetup_backend/internal/outcome"
+	"meetup_backend/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type MeetupHandler struct {
+	DB       *gorm.DB
+	Config   *config.Config
+	Outcomes *outcome.Service
+}
+
+func NewMeetupHandler(db *gorm.DB, cfg *config.Config, outcomes *outcome.Service) *MeetupHandler {
+	return &MeetupHandler{DB: db, Config: cfg, Outcomes: outcomes}
+}
+
+// ReportOutcomeRequest defines the payload for reporting how a meetup went.
+// AbsentUserID empty means the other participant; your own ID admits you did not come.
+type ReportOutcomeRequest struct {
+	Outcome      string `json:"outcome"` // completed, no_show
+	AbsentUserID *uint  `json:"absent_user_id"`
+}
+
+// MeetupPartner is another participant of one of my meetups
+type MeetupPartner struct {
+	models.UserSummary
+	ReviewedByMe bool `json:"reviewed_by_me"`
+	NoShow       bool `json:"no_show"` // Did not show up, as settled
+}
+
+// MeetupResult is a confirmed meetup as seen by one of its participants
+type MeetupResult struct {
+	ID               uint            `json:"id"`
+	ChatRoomID       uint            `json:"chat_room_id"`
+	PointsCost       int             `json:"points_cost"`
+	ConfirmedAt      time.Time       `json:"confirmed_at"`
+	ReviewDeadline   time.Time       `json:"review_deadline"`
+	CanReview        bool            `json:"can_review"` // Someone is still waiting for my review
+	Status           string          `json:"status"`
+	MyOutcome        string          `json:"my_outcome,omitempty"`
+	NoShowByMe       bool            `json:"no_show_by_me"`
+	OutcomeDeadline  time.Time       `json:"outcome_deadline"`
+	CanReportOutcome bool            `json:"can_report_outcome"`
+	Partners         []MeetupPartner `json:"partners"`
+}
+
+// ListMeetups - GET /api/meetups?cursor=&limit=
+// Confirmed meetups of the current user, newest first
+func (h *MeetupHandler) ListMeetups(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	cursor, limit, err := pageParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	mine := h.DB.Model(&models.MeetupParticipant{}).Select("meetup_id").Where("user_id = ?", userID)
+	query := h.DB.Preload("Participants.User", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped().Select("id, username, full_name, image_url")
+	}).
+		Where("id IN (?)", mine).
+		Order("id desc").
+		Limit(limit)
+	if cursor != 0 {
+		query = query.Where("id < ?", cursor)
+	}
+
+	var meetups []models.Meetup
+	if err := query.Find(&meetups).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch meetups"})
+	}
+
+	meetupIDs := make([]uint, 0, len(meetups))
+	for _, m := range meetups {
+		meetupIDs = append(meetupIDs, m.ID)
+	}
+	var reviews []models.Review
+	if len(meetupIDs) > 0 {
+		h.DB.Select("meetup_id, reviewee_id").
+			Where("reviewer_id = ? AND meetup_id IN ?", userID, meetupIDs).
+			Find(&reviews)
+	}
+	reviewed := map[uint]map[uint]bool{} // meetup -> reviewee
+	for _, r := range reviews {
+		if reviewed[r.MeetupID] == nil {
+			reviewed[r.MeetupID] = map[uint]bool{}
+		}
+		reviewed[r.MeetupID][r.RevieweeID] = true
+	}
+
+	now := time.Now()
+	results := make([]MeetupResult, 0, len(meetups))
+	for _, m := range meetups {
+		result := MeetupResult{
+			ID:              m.ID,
+			ChatRoomID:      m.ChatRoomID,
+			PointsCost:      m.PointsCost,
+			ConfirmedAt:     m.ConfirmedAt,
+			ReviewDeadline:  m.ConfirmedAt.Add(h.Config.ReviewWindow),
+			Status:          m.Status,
+			OutcomeDeadline: h.Outcomes.Deadline(&m),
+			Partners:        []MeetupPartner{},
+		}
+		for _, p := range m.Participants {
+			if p.UserID == userID {
+				// Partners' reports stay private, only the settled result is shown
+				result.MyOutcome = p.Outcome
+				result.NoShowByMe = p.NoShow
+				result.CanReportOutcome = m.Status == models.MeetupConfirmed && p.ReportedAt == nil &&
+					now.Before(result.OutcomeDeadline)
+				continue
+			}
+			partner := MeetupPartner{
+				UserSummary:  newUserSummary(p.User),
+				ReviewedByMe: reviewed[m.ID][p.UserID],
+				NoShow:       p.NoShow,
+			}
+			if !partner.ReviewedByMe && now.Before(result.ReviewDeadline) {
+				result.CanReview = true
+			}
+			result.Partners = append(result.Partners, partner)
+		}
+		results = append(results, result)
+	}
+
+	var nextCursor *uint
+	if len(meetups) == limit {
+		nextCursor = &meetups[len(meetups)-1].ID
+	}
+
+	return c.JSON(fiber.Map{"data": results, "next_cursor": nextCursor})
+}
+
+// ReportOutcome - POST /api/meetups/:id/outcome
+// Each participant reports once whether the meetup happened, within
+// MEETUP_OUTCOME_WINDOW of the confirmation
+func (h *MeetupHandler) ReportOutcome(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid meetup ID"})
+	}
+
+	var req ReportOutcomeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	meetup, err := h.Outcomes.Report(uint(id), userID, req.Outcome, req.AbsentUserID)
+	switch {
+	case errors.Is(err, outcome.ErrMeetupNotFound), errors.Is(err, outcome.ErrNotParticipant):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Meetup not found"})
+	case errors.Is(err, outcome.ErrInvalidOutcome), errors.Is(err, outcome.ErrInvalidAbsentUser):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, outcome.ErrAlreadyReported):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "You already reported the outcome of this meetup"})
+	case errors.Is(err, outcome.ErrAlreadySettled):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "This meetup is already settled"})
+	case errors.Is(err, outcome.ErrWindowClosed):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "The time to report this meetup has passed"})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not report outcome"})
+	}
+
+	message := "Outcome recorded"
+	switch meetup.Status {
+	case models.MeetupDisputed:
+		message = "Your report differs from the other side's. A moderator will review the meetup."
+	case models.MeetupCompleted, models.MeetupNoShow:
+		message = "Meetup settled"
+	}
+
+	return c.JSON(fiber.Map{
+		"message": message,
+		"data": fiber.Map{
+			"meetup_id": meetup.ID,
+			"status":    meetup.Status,
+		},
+	})
+}
@@ -0,0 +1,479 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"meetup_backend/internal/outcome"
+	"meetup_backend/internal/ws"
+	"meetup_backend/models"
+	"meetup_backend/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type ModerationHandler struct {
+	DB       *gorm.DB
+	Hub      *ws.Hub
+	Outcomes *outcome.Service
+}
+
+func NewModerationHandler(db *gorm.DB, hub *ws.Hub, outcomes *outcome.Service) *ModerationHandler {
+	return &ModerationHandler{DB: db, Hub: hub, Outcomes: outcomes}
+}
+
+// AssignReportRequest defines the payload for assigning a report.
+// AssigneeID empty assigns the report to the caller.
+type AssignReportRequest struct {
+	AssigneeID *uint `json:"assignee_id"`
+}
+
+// UpdateReportStatusRequest defines the payload for moving a report back to open or in_review
+type UpdateReportStatusRequest struct {
+	Status string `json:"status"`
+}
+
+// ResolveReportRequest defines the payload for resolving a report
+type ResolveReportRequest struct {
+	Action       string     `json:"action"` // dismiss, hide_product, warn, suspend
+	Note         string     `json:"note"`
+	SuspendUntil *time.Time `json:"suspend_until"` // Required for suspend
+}
+
+// ResolveDisputeRequest defines the payload for settling a disputed meetup
+type ResolveDisputeRequest struct {
+	Verdict      string `json:"verdict"`         // completed, no_show, cancelled
+	NoShowUserID *uint  `json:"no_show_user_id"` // Required for no_show
+	Note         string `json:"note"`
+}
+
+// Resolution actions
+const (
+	ResolutionDismiss     = "dismiss"
+	ResolutionHideProduct = "hide_product"
+	ResolutionWarn        = "warn"
+	ResolutionSuspend     = "suspend"
+)
+
+// ListReports - GET /api/admin/reports
+// Filters: status, target_type, reason, reported_user_id, assignee (an ID, "me" or "none")
+func (h *ModerationHandler) ListReports(c *fiber.Ctx) error {
+	moderatorID := c.Locals("user_id").(uint)
+	page, limit := getPagination(c)
+
+	query := h.DB.Model(&models.Report{})
+
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	} else {
+		// The queue shows unfinished reports unless asked otherwise
+		query = query.Where("status IN ?", []string{models.ReportStatusOpen, models.ReportStatusInReview})
+	}
+	if targetType := c.Query("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if reason := c.Query("reason"); reason != "" {
+		query = query.Where("reason = ?", reason)
+	}
+	if reportedUserID := c.QueryInt("reported_user_id"); reportedUserID > 0 {
+		query = query.Where("reported_user_id = ?", reportedUserID)
+	}
+	switch assignee := c.Query("assignee"); assignee {
+	case "":
+	case "me":
+		query = query.Where("assignee_id = ?", moderatorID)
+	case "none":
+		query = query.Where("assignee_id IS NULL")
+	default:
+		query = query.Where("assignee_id = ?", c.QueryInt("assignee"))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch reports"})
+	}
+
+	// Oldest first, so the queue is worked in order
+	var reports []models.Report
+	if err := query.Order("created_at asc").
+		Limit(limit).
+		Offset((page - 1) * limit).
+		Find(&reports).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch reports"})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": reports,
+		"meta": models.NewPaginationMeta(page, limit, total),
+	})
+}
+
+// GetReport - GET /api/admin/reports/:id
+// Includes the reported user, the product (for product reports) and how many
+// other reports the same target has
+func (h *ModerationHandler) GetReport(c *fiber.Ctx) error {
+	report, err := h.findReport(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Report not found"})
+	}
+
+	var reportedUser models.User
+	h.DB.Unscoped().First(&reportedUser, report.ReportedUserID)
+
+	var product *models.Product
+	if report.TargetType == models.ReportTargetProduct {
+		var p models.Product
+		if err := h.DB.Unscoped().First(&p, report.TargetID).Error; err == nil {
+			product = &p
+		}
+	}
+
+	var targetReports int64
+	h.DB.Model(&models.Report{}).
+		Where("target_type = ? AND target_id = ?", report.TargetType, report.TargetID).
+		Count(&targetReports)
+
+	var userReports int64
+	h.DB.Model(&models.Report{}).Where("reported_user_id = ?", report.ReportedUserID).Count(&userReports)
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"report":         report,
+			"reported_user":  reportedUser,
+			"product":        product,
+			"target_reports": targetReports,
+			"user_reports":   userReports,
+		},
+	})
+}
+
+// AssignReport - POST /api/admin/reports/:id/assign
+// Assigning an open report moves it to in_review
+func (h *ModerationHandler) AssignReport(c *fiber.Ctx) error {
+	moderatorID := c.Locals("user_id").(uint)
+
+	var req AssignReportRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+		}
+	}
+
+	report, err := h.findReport(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Report not found"})
+	}
+	if report.IsClosed() {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Report is already closed"})
+	}
+
+	assigneeID := moderatorID
+	if req.AssigneeID != nil {
+		var assignee models.User
+		if err := h.DB.Select("id, role").First(&assignee, *req.AssigneeID).Error; err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Assignee not found"})
+		}
+		if !utils.HasPermission(assignee.Role, utils.PermModerateReports) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Assignee cannot moderate reports"})
+		}
+		assigneeID = assignee.ID
+	}
+
+	updates := map[string]interface{}{"assignee_id": assigneeID}
+	if report.Status == models.ReportStatusOpen {
+		updates["status"] = models.ReportStatusInReview
+	}
+	if err := h.DB.Model(report).Updates(updates).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not assign report"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Report assigned", "data": report})
+}
+
+// UpdateReportStatus - PUT /api/admin/reports/:id/status
+// Only moves between open and in_review; closing a report goes through resolve
+func (h *ModerationHandler) UpdateReportStatus(c *fiber.Ctx) error {
+	var req UpdateReportStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	if req.Status != models.ReportStatusOpen && req.Status != models.ReportStatusInReview {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Status must be open or in_review; use resolve to close a report"})
+	}
+
+	report, err := h.findReport(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Report not found"})
+	}
+	if report.IsClosed() {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Report is already closed"})
+	}
+
+	updates := map[string]interface{}{"status": req.Status}
+	if req.Status == models.ReportStatusOpen {
+		// Back to the queue for anyone to pick up
+		updates["assignee_id"] = nil
+	}
+	if err := h.DB.Model(report).Updates(updates).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update report"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Report updated", "data": report})
+}
+
+// ResolveReport - POST /api/admin/reports/:id/resolve
+// Applies the action and closes every unfinished report on the same target
+func (h *ModerationHandler) ResolveReport(c *fiber.Ctx) error {
+	moderatorID := c.Locals("user_id").(uint)
+
+	var req ResolveReportRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	report, err := h.findReport(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Report not found"})
+	}
+	if report.IsClosed() {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Report is already closed"})
+	}
+
+	switch req.Action {
+	case ResolutionDismiss, ResolutionWarn:
+	case ResolutionHideProduct:
+		if report.TargetType != models.ReportTargetProduct {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "hide_product only applies to product reports"})
+		}
+	case ResolutionSuspend:
+		if req.SuspendUntil == nil || req.SuspendUntil.Before(time.Now()) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "suspend_until must be in the future"})
+		}
+		if report.ReportedUserID == moderatorID {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "You cannot suspend yourself"})
+		}
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Action must be dismiss, hide_product, warn or suspend"})
+	}
+
+	// Warnings and suspensions need the account; it may have been deleted since
+	var reportedUser models.User
+	if req.Action == ResolutionWarn || req.Action == ResolutionSuspend {
+		if err := h.DB.First(&reportedUser, report.ReportedUserID).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Reported user not found"})
+		}
+		// Only admins can act against other staff
+		if utils.HasPermission(reportedUser.Role, utils.PermModerateReports) && !utils.Can(c, utils.PermManageUsers) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You cannot take this action against staff"})
+		}
+	}
+
+	status := models.ReportStatusResolved
+	if req.Action == ResolutionDismiss {
+		status = models.ReportStatusDismissed
+	}
+	now := time.Now()
+
+	var closed int64
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Report{}).
+			Where("target_type = ? AND target_id = ? AND status IN ?", report.TargetType, report.TargetID,
+				[]string{models.ReportStatusOpen, models.ReportStatusInReview}).
+			Updates(map[string]interface{}{
+				"status":          status,
+				"resolution":      req.Action,
+				"resolution_note": req.Note,
+				"resolved_by":     moderatorID,
+				"resolved_at":     now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		closed = result.RowsAffected
+
+		switch req.Action {
+		case ResolutionDismiss:
+			// Undo the automatic hiding; products hidden by a moderator stay hidden
+			if report.TargetType == models.ReportTargetProduct {
+				return tx.Model(&models.Product{}).
+					Where("id = ? AND hidden_reason = ?", report.TargetID, "reports").
+					Updates(map[string]interface{}{"hidden_at": nil, "hidden_reason": ""}).Error
+			}
+		case ResolutionHideProduct:
+			return tx.Model(&models.Product{}).
+				Where("id = ?", report.TargetID).
+				Updates(map[string]interface{}{"hidden_at": now, "hidden_reason": "moderator"}).Error
+		case ResolutionWarn:
+			return tx.Model(&reportedUser).Updates(map[string]interface{}{
+				"warning_count":  gorm.Expr("warning_count + 1"),
+				"last_warned_at": now,
+			}).Error
+		case ResolutionSuspend:
+			return utils.BanUser(tx, &reportedUser, moderatorID, suspendReason(report, req.Note), req.SuspendUntil)
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not resolve report"})
+	}
+
+	switch req.Action {
+	case ResolutionWarn:
+		h.Hub.SendToUser(reportedUser.ID, warningEvent(report, req.Note))
+	case ResolutionSuspend:
+		h.Hub.DisconnectUser(reportedUser.ID, "banned")
+	}
+	log.Printf("Report %d resolved by moderator %d: %s (%d reports closed)", report.ID, moderatorID, req.Action, closed)
+
+	h.DB.First(report, report.ID)
+
+	return c.JSON(fiber.Map{
+		"message":        "Report resolved",
+		"data":           report,
+		"reports_closed": closed,
+	})
+}
+
+// ListDisputes - GET /api/admin/disputes
+// Meetups whose participants reported different outcomes. Filter: status
+// (disputed by default; completed, no_show or cancelled for settled disputes).
+func (h *ModerationHandler) ListDisputes(c *fiber.Ctx) error {
+	page, limit := getPagination(c)
+
+	query := h.DB.Model(&models.Meetup{}).Where("disputed_at IS NOT NULL")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	} else {
+		query = query.Where("status = ?", models.MeetupDisputed)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch disputes"})
+	}
+
+	// Oldest first, so the queue is worked in order
+	var meetups []models.Meetup
+	if err := query.Preload("Participants.User", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).
+		Order("disputed_at asc").
+		Limit(limit).
+		Offset((page - 1) * limit).
+		Find(&meetups).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch disputes"})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": meetups,
+		"meta": models.NewPaginationMeta(page, limit, total),
+	})
+}
+
+// GetDispute - GET /api/admin/disputes/:id
+// The meetup with each participant's report, no-show history and open reports
+// against them
+func (h *ModerationHandler) GetDispute(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid meetup ID"})
+	}
+
+	var meetup models.Meetup
+	if err := h.DB.Preload("Participants.User", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).
+		Where("disputed_at IS NOT NULL").
+		First(&meetup, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Dispute not found"})
+	}
+
+	userReports := map[uint]int64{}
+	for _, p := range meetup.Participants {
+		var count int64
+		h.DB.Model(&models.Report{}).
+			Where("reported_user_id = ? AND status IN ?", p.UserID, []string{models.ReportStatusOpen, models.ReportStatusInReview}).
+			Count(&count)
+		userReports[p.UserID] = count
+	}
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"meetup":       meetup,
+			"user_reports": userReports,
+		},
+	})
+}
+
+// ResolveDispute - POST /api/admin/disputes/:id/resolve
+// Settles the meetup: no_show refunds the others and counts a no-show for the
+// given participant, cancelled refunds everyone, completed refunds nobody
+func (h *ModerationHandler) ResolveDispute(c *fiber.Ctx) error {
+	moderatorID := c.Locals("user_id").(uint)
+
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid meetup ID"})
+	}
+
+	var req ResolveDisputeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	meetup, err := h.Outcomes.Resolve(uint(id), moderatorID, req.Verdict, req.NoShowUserID, req.Note)
+	switch {
+	case errors.Is(err, outcome.ErrMeetupNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Dispute not found"})
+	case errors.Is(err, outcome.ErrNotDisputed):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Meetup is not disputed"})
+	case errors.Is(err, outcome.ErrOwnDispute):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You cannot resolve a dispute you are part of"})
+	case errors.Is(err, outcome.ErrInvalidVerdict), errors.Is(err, outcome.ErrInvalidAbsentUser):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not resolve dispute"})
+	}
+
+	log.Printf("Meetup %d dispute resolved by moderator %d: %s", meetup.ID, moderatorID, meetup.Status)
+
+	return c.JSON(fiber.Map{
+		"message": "Dispute resolved",
+		"data":    meetup,
+	})
+}
+
+// findReport loads the report from the :id route param
+func (h *ModerationHandler) findReport(c *fiber.Ctx) (*models.Report, error) {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return nil, err
+	}
+
+	var report models.Report
+	if err := h.DB.First(&report, id).Error; err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func suspendReason(report *models.Report, note string) string {
+	if note != "" {
+		return note
+	}
+	return "Suspended after report: " + report.Reason
+}
+
+// warningEvent tells the warned user why, without revealing who reported them
+func warningEvent(report *models.Report, note string) []byte {
+	eventJSON, _ := json.Marshal(map[string]interface{}{
+		"type":        "moderation_warning",
+		"reason":      report.Reason,
+		"target_type": report.TargetType,
+		"target_id":   report.TargetID,
+		"note":        note,
+	})
+	return eventJSON
+}
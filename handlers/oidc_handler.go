@@ -0,0 +1,391 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"meetup_backend/internal/oidc"
+	"meetup_backend/internal/points"
+	"meetup_backend/models"
+	"meetup_backend/utils"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// OIDCCallbackRequest is what the identity provider sends back to the redirect URL.
+// It may arrive as query parameters (browser redirect) or as a JSON body (SPA forwarding it).
+type OIDCCallbackRequest struct {
+	Code             string `json:"code" query:"code"`
+	State            string `json:"state" query:"state"`
+	Error            string `json:"error" query:"error"`
+	ErrorDescription string `json:"error_description" query:"error_description"`
+}
+
+const oidcRequestTimeout = 15 * time.Second
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-z0-9_.]`)
+
+// ListOIDCProviders - GET /api/auth/oidc/providers
+func (h *AuthHandler) ListOIDCProviders(c *fiber.Ctx) error {
+	names := make([]string, 0, len(h.OIDC))
+	for name := range h.OIDC {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return c.JSON(fiber.Map{"data": names})
+}
+
+// OIDCLogin - GET /api/auth/oidc/:provider/login?device_name=...
+// Returns the URL where the user signs in at the provider
+func (h *AuthHandler) OIDCLogin(c *fiber.Ctx) error {
+	provider, ok := h.OIDC[c.Params("provider")]
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Unknown login provider"})
+	}
+	return h.startOIDC(c, provider, nil, c.Query("device_name"))
+}
+
+// OIDCLink - POST /api/auth/oidc/:provider/link
+// Like OIDCLogin, but the callback links the provider account to the current user
+func (h *AuthHandler) OIDCLink(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	provider, ok := h.OIDC[c.Params("provider")]
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Unknown login provider"})
+	}
+	return h.startOIDC(c, provider, &userID, "")
+}
+
+// startOIDC stores state, nonce and PKCE verifier for the callback and builds the authorization URL
+func (h *AuthHandler) startOIDC(c *fiber.Ctx, provider *oidc.Provider, userID *uint, deviceName string) error {
+	state, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not start login"})
+	}
+	nonce, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not start login"})
+	}
+	verifier, err := oidc.GenerateCodeVerifier()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not start login"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.UserContext(), oidcRequestTimeout)
+	defer cancel()
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, oidc.CodeChallengeS256(verifier))
+	if err != nil {
+		log.Printf("OIDC provider %s unavailable: %v", provider.Name(), err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Login provider is unavailable"})
+	}
+
+	expiresAt := time.Now().Add(h.Config.OIDCStateExpiration)
+	if err := h.DB.Create(&models.OAuthState{
+		StateHash:    utils.HashToken(state),
+		Provider:     provider.Name(),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		UserID:       userID,
+		DeviceName:   deviceName,
+		ExpiresAt:    expiresAt,
+	}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not start login"})
+	}
+
+	// Clean up requests that were never finished
+	h.DB.Where("expires_at < ?", time.Now().Add(-24*time.Hour)).Delete(&models.OAuthState{})
+
+	return c.JSON(fiber.Map{
+		"authorization_url": authURL,
+		"expires_at":        expiresAt,
+	})
+}
+
+// OIDCCallback - GET/POST /api/auth/oidc/:provider/callback
+// Completes a login (or a link started with OIDCLink) after the user signed in at the provider
+func (h *AuthHandler) OIDCCallback(c *fiber.Ctx) error {
+	provider, ok := h.OIDC[c.Params("provider")]
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Unknown login provider"})
+	}
+
+	var req OIDCCallbackRequest
+	if c.Method() == fiber.MethodPost {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+		}
+	} else if err := c.QueryParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	if req.Error != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Sign-in was cancelled or denied at the provider", "reason": req.Error})
+	}
+	if req.Code == "" || req.State == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Code and state are required"})
+	}
+
+	// Consume the state; it is single-use and bound to the provider it was created for
+	var state models.OAuthState
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("state_hash = ? AND provider = ?", utils.HashToken(req.State), provider.Name()).First(&state).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&models.OAuthState{}).
+			Where("id = ? AND used_at IS NULL AND expires_at > ?", state.ID, time.Now()).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Sign-in request is invalid or has expired. Please try again."})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not complete sign-in"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.UserContext(), oidcRequestTimeout)
+	defer cancel()
+
+	rawIDToken, err := provider.Exchange(ctx, req.Code, state.CodeVerifier)
+	if err != nil {
+		log.Printf("OIDC code exchange with %s failed: %v", provider.Name(), err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Could not complete sign-in with the provider"})
+	}
+
+	claims, err := provider.VerifyIDToken(ctx, rawIDToken, state.Nonce)
+	if err != nil {
+		log.Printf("OIDC ID token from %s rejected: %v", provider.Name(), err)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Identity token from the provider is invalid"})
+	}
+
+	if state.UserID != nil {
+		return h.linkIdentity(c, provider.Name(), *state.UserID, claims)
+	}
+	return h.loginWithIdentity(c, provider.Name(), claims, state.DeviceName)
+}
+
+// loginWithIdentity signs in the user linked to the provider account. Unknown accounts are
+// linked to the user with the same (provider-verified) email, or a new user is created.
+func (h *AuthHandler) loginWithIdentity(c *fiber.Ctx, provider string, claims *oidc.IDTokenClaims, deviceName string) error {
+	var user models.User
+
+	var identity models.UserIdentity
+	err := h.DB.Where("provider = ? AND subject = ?", provider, claims.Subject).First(&identity).Error
+	switch {
+	case err == nil:
+		if err := h.DB.First(&user, identity.UserID).Error; err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+		}
+		now := time.Now()
+		h.DB.Model(&identity).Updates(map[string]interface{}{"last_login_at": now, "email": claims.Email})
+
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		// Matching by email is only safe when the provider vouches for it
+		if claims.Email == "" || !claims.EmailVerified {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Your email address is not verified at this provider"})
+		}
+		if err := h.linkOrCreateUser(provider, claims, &user); err != nil {
+			log.Printf("OIDC account setup for %s failed: %v", claims.Email, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not complete sign-in"})
+		}
+
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not complete sign-in"})
+	}
+
+	if user.IsBanned() {
+		return c.Status(fiber.StatusForbidden).JSON(utils.BannedResponse(&user))
+	}
+
+	// The provider replaces the password, not the second factor
+	if user.TOTPEnabled {
+		return h.mfaChallenge(c, &user, deviceName)
+	}
+
+	return h.completeLogin(c, &user, deviceName)
+}
+
+// linkOrCreateUser links the provider account to the user with the same email,
+// creating a verified, password-less user when there is none
+func (h *AuthHandler) linkOrCreateUser(provider string, claims *oidc.IDTokenClaims, user *models.User) error {
+	claimedUnverified := false
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		err := tx.Where("email = ?", claims.Email).First(user).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			username, err := uniqueUsername(tx, claims.Email)
+			if err != nil {
+				return err
+			}
+			*user = models.User{
+				Username:        username,
+				Email:           claims.Email,
+				Password:        "", // Social login only until the user sets one via forgot-password
+				FullName:        claims.Name,
+				ImageURL:        claims.Picture,
+				Role:            "user",
+				IsVerified:      true,
+				EmailVerifiedAt: &now,
+			}
+			if err := tx.Create(user).Error; err != nil {
+				return err
+			}
+			if err := points.GrantSignupBonus(tx, user.ID); err != nil {
+				return err
+			}
+
+		case err != nil:
+			return err
+
+		case !user.IsVerified:
+			// Whoever registered this address never proved they own it, but the provider
+			// just did. Drop the password and sessions so a squatter cannot keep access.
+			if err := tx.Model(user).Updates(map[string]interface{}{
+				"password":          "",
+				"is_verified":       true,
+				"email_verified_at": now,
+			}).Error; err != nil {
+				return err
+			}
+			if err := utils.RevokeUserSessions(tx, user.ID, 0, "email_claimed"); err != nil {
+				return err
+			}
+			claimedUnverified = true
+		}
+
+		return tx.Create(&models.UserIdentity{
+			UserID:      user.ID,
+			Provider:    provider,
+			Subject:     claims.Subject,
+			Email:       claims.Email,
+			LastLoginAt: &now,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if claimedUnverified {
+		h.Hub.DisconnectUser(user.ID, "email_claimed")
+	}
+	return nil
+}
+
+// uniqueUsername derives a free username from the local part of an email address
+func uniqueUsername(tx *gorm.DB, email string) (string, error) {
+	base := strings.ToLower(strings.SplitN(email, "@", 2)[0])
+	base = usernameInvalidChars.ReplaceAllString(base, "")
+	if len(base) > 40 {
+		base = base[:40]
+	}
+	if len(base) < 3 {
+		base = "user"
+	}
+
+	candidate := base
+	for i := 0; i < 5; i++ {
+		var count int64
+		if err := tx.Model(&models.User{}).Unscoped().Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+
+		suffix, err := utils.GenerateRandomToken(3)
+		if err != nil {
+			return "", err
+		}
+		candidate = base + "_" + suffix
+	}
+	return "", errors.New("could not find a free username")
+}
+
+// linkIdentity attaches the provider account to an existing, already authenticated user
+func (h *AuthHandler) linkIdentity(c *fiber.Ctx, provider string, userID uint, claims *oidc.IDTokenClaims) error {
+	var existing models.UserIdentity
+	if err := h.DB.Where("provider = ? AND subject = ?", provider, claims.Subject).First(&existing).Error; err == nil {
+		if existing.UserID == userID {
+			return c.JSON(fiber.Map{"message": "Account is already linked"})
+		}
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "This account is already linked to another user"})
+	}
+
+	var count int64
+	h.DB.Model(&models.UserIdentity{}).Where("user_id = ? AND provider = ?", userID, provider).Count(&count)
+	if count > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A different account from this provider is already linked. Unlink it first."})
+	}
+
+	identity := models.UserIdentity{
+		UserID:   userID,
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+	if err := h.DB.Create(&identity).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not link account"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Account linked successfully", "data": identity})
+}
+
+// GetIdentities - GET /api/auth/identities
+// Lists the provider accounts linked to the current user
+func (h *AuthHandler) GetIdentities(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var identities []models.UserIdentity
+	if err := h.DB.Where("user_id = ?", userID).Order("created_at asc").Find(&identities).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch linked accounts"})
+	}
+
+	return c.JSON(fiber.Map{"data": identities})
+}
+
+// UnlinkIdentity - DELETE /api/auth/identities/:provider
+// Refuses to remove the last way to sign in for users without a password
+func (h *AuthHandler) UnlinkIdentity(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	provider := c.Params("provider")
+
+	var identity models.UserIdentity
+	if err := h.DB.Where("user_id = ? AND provider = ?", userID, provider).First(&identity).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No linked account for this provider"})
+	}
+
+	var user models.User
+	if err := h.DB.Select("id", "password").First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if user.Password == "" {
+		var count int64
+		h.DB.Model(&models.UserIdentity{}).Where("user_id = ?", userID).Count(&count)
+		if count <= 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "This is your only way to sign in. Set a password via forgot-password before unlinking it."})
+		}
+	}
+
+	if err := h.DB.Delete(&identity).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not unlink account"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Account unlinked successfully"})
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"meetup_backend/config"
+	"meetup_backend/internal/sms"
+	"meetup_backend/models"
+	"meetup_backend/utils"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type PhoneHandler struct {
+	DB     *gorm.DB
+	Config *config.Config
+	SMS    sms.Gateway
+}
+
+func NewPhoneHandler(db *gorm.DB, cfg *config.Config, gateway sms.Gateway) *PhoneHandler {
+	return &PhoneHandler{DB: db, Config: cfg, SMS: gateway}
+}
+
+// RequestPhoneCodeRequest defines the payload for adding or changing a phone number
+type RequestPhoneCodeRequest struct {
+	Phone string `json:"phone"`
+}
+
+// VerifyPhoneRequest defines the payload for confirming the SMS code
+type VerifyPhoneRequest struct {
+	Code string `json:"code"`
+}
+
+// ChatRequirementsRequest defines who may start a chat with the current user
+type ChatRequirementsRequest struct {
+	RequireVerifiedPhone bool `json:"require_verified_phone"`
+}
+
+const phoneOTPDigits = 6
+
+// E.164: "+" followed by country code and subscriber number, at most 15 digits
+var phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+var errPhoneTaken = errors.New("phone number already in use")
+
+// normalizePhone strips common formatting characters such as spaces and dashes
+func normalizePhone(phone string) string {
+	return strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", ".", "").Replace(strings.TrimSpace(phone))
+}
+
+// RequestCode - POST /api/users/me/phone
+// Sends a verification code to a new phone number. The number is saved on the
+// account only after it has been confirmed with VerifyCode.
+func (h *PhoneHandler) RequestCode(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req RequestPhoneCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	phone := normalizePhone(req.Phone)
+	if !phonePattern.MatchString(phone) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Phone number must be in international format, e.g. +6281234567890"})
+	}
+
+	var user models.User
+	if err := h.DB.Select("id", "phone", "phone_verified").First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if user.Phone != nil && *user.Phone == phone && user.PhoneVerified {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "This phone number is already verified"})
+	}
+
+	var taken int64
+	h.DB.Model(&models.User{}).Where("phone = ? AND id != ?", phone, userID).Count(&taken)
+	if taken > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Phone number is already used by another account"})
+	}
+
+	// Throttle: SMS costs money and can be used to harass the number's owner
+	var last models.PhoneVerification
+	if err := h.DB.Where("user_id = ?", userID).Order("created_at desc").First(&last).Error; err == nil {
+		wait := time.Until(last.CreatedAt.Add(h.Config.PhoneOTPResendWait))
+		if wait > 0 {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error":       "Please wait before requesting another code",
+				"retry_after": int(wait.Seconds()) + 1,
+			})
+		}
+	}
+
+	code, err := utils.GenerateNumericCode(phoneOTPDigits)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not send code"})
+	}
+
+	verification := models.PhoneVerification{
+		UserID:    userID,
+		Phone:     phone,
+		CodeHash:  utils.HashToken(code),
+		ExpiresAt: time.Now().Add(h.Config.PhoneOTPExpiration),
+	}
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		// Only the most recent code stays valid
+		if err := tx.Model(&models.PhoneVerification{}).
+			Where("user_id = ? AND used_at IS NULL", userID).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&verification).Error
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not send code"})
+	}
+
+	err = h.SMS.Send(sms.Message{
+		To:   phone,
+		Body: fmt.Sprintf("Your Meetup verification code is %s. It expires in %s.", code, h.Config.PhoneOTPExpiration),
+	})
+	if err != nil {
+		log.Printf("Failed to send phone verification code to user %d: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not send code"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":    "Verification code sent",
+		"expires_at": verification.ExpiresAt,
+	})
+}
+
+// VerifyCode - POST /api/users/me/phone/verify
+// Confirms the latest code and saves the phone number as verified
+func (h *PhoneHandler) VerifyCode(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req VerifyPhoneRequest
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Code is required"})
+	}
+
+	var verification models.PhoneVerification
+	if err := h.DB.Where("user_id = ? AND used_at IS NULL", userID).Order("created_at desc").First(&verification).Error; err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No pending verification. Please request a new code."})
+	}
+	if time.Now().After(verification.ExpiresAt) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Code has expired. Please request a new code."})
+	}
+
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(strings.TrimSpace(req.Code))), []byte(verification.CodeHash)) != 1 {
+		// Count the attempt atomically so parallel guesses cannot exceed the limit
+		result := h.DB.Model(&models.PhoneVerification{}).
+			Where("id = ? AND attempts < ?", verification.ID, h.Config.PhoneOTPMaxAttempts).
+			Update("attempts", gorm.Expr("attempts + 1"))
+		remaining := h.Config.PhoneOTPMaxAttempts - verification.Attempts - 1
+		if result.RowsAffected == 0 || remaining <= 0 {
+			h.DB.Model(&verification).Update("used_at", time.Now())
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Too many wrong codes. Please request a new code."})
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":              "Invalid code",
+			"attempts_remaining": remaining,
+		})
+	}
+
+	now := time.Now()
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.PhoneVerification{}).
+			Where("id = ? AND used_at IS NULL AND attempts < ?", verification.ID, h.Config.PhoneOTPMaxAttempts).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		// Someone else may have verified the same number in the meantime
+		var taken int64
+		tx.Model(&models.User{}).Where("phone = ? AND id != ?", verification.Phone, userID).Count(&taken)
+		if taken > 0 {
+			return errPhoneTaken
+		}
+
+		return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"phone":             verification.Phone,
+			"phone_verified":    true,
+			"phone_verified_at": now,
+		}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No pending verification. Please request a new code."})
+	}
+	if errors.Is(err, errPhoneTaken) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Phone number is already used by another account"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not verify phone number"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Phone number verified",
+		"data": fiber.Map{
+			"phone":          verification.Phone,
+			"phone_verified": true,
+		},
+	})
+}
+
+// RemovePhone - DELETE /api/users/me/phone
+func (h *PhoneHandler) RemovePhone(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	if err := h.DB.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"phone":             nil,
+		"phone_verified":    false,
+		"phone_verified_at": nil,
+	}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not remove phone number"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Phone number removed"})
+}
+
+// UpdateChatRequirements - PUT /api/users/me/chat-requirements
+// Lets a seller only accept new chats from buyers with a verified phone number
+func (h *PhoneHandler) UpdateChatRequirements(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req ChatRequirementsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	if err := h.DB.Model(&models.User{}).Where("id = ?", userID).
+		Update("require_verified_phone", req.RequireVerifiedPhone).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update settings"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Settings updated",
+		"data":    fiber.Map{"require_verified_phone": req.RequireVerifiedPhone},
+	})
+}
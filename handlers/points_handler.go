@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"meetup_backend/models"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type PointsHandler struct {
+	DB *gorm.DB
+}
+
+func NewPointsHandler(db *gorm.DB) *PointsHandler {
+	return &PointsHandler{DB: db}
+}
+
+// GetHistory - GET /api/points/history?cursor=&limit=
+// The current user's balance and ledger, newest first
+func (h *PointsHandler) GetHistory(c *fiber.Ctx) error {
+	return h.listTransactions(c, c.Locals("user_id").(uint))
+}
+
+// GetUserHistory - GET /api/admin/users/:id/points?cursor=&limit=
+func (h *PointsHandler) GetUserHistory(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	return h.listTransactions(c, uint(id))
+}
+
+func (h *PointsHandler) listTransactions(c *fiber.Ctx, userID uint) error {
+	cursor, limit, err := pageParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var user models.User
+	if err := h.DB.Unscoped().Select("id, points").First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	query := h.DB.Where("user_id = ?", user.ID).Order("id desc").Limit(limit)
+	if cursor != 0 {
+		query = query.Where("id < ?", cursor)
+	}
+	transactions := []models.PointTransaction{}
+	if err := query.Find(&transactions).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch points history"})
+	}
+
+	var nextCursor *uint
+	if len(transactions) == limit {
+		nextCursor = &transactions[len(transactions)-1].ID
+	}
+
+	return c.JSON(fiber.Map{
+		"balance":     user.Points,
+		"data":        transactions,
+		"next_cursor": nextCursor,
+	})
+}
@@ -1,19 +1,31 @@
 package handlers
 
 import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"meetup_backend/internal/geo"
+	"meetup_backend/internal/ws"
 	"meetup_backend/models"
+	"meetup_backend/utils"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProductHandler struct {
-	DB *gorm.DB
+	DB  *gorm.DB
+	Geo *geo.Gazetteer
+	Hub *ws.Hub
 }
 
-func NewProductHandler(db *gorm.DB) *ProductHandler {
-	return &ProductHandler{DB: db}
+func NewProductHandler(db *gorm.DB, gazetteer *geo.Gazetteer, hub *ws.Hub) *ProductHandler {
+	return &ProductHandler{DB: db, Geo: gazetteer, Hub: hub}
 }
 
 // CreateProductRequest
@@ -25,6 +37,84 @@ type CreateProductRequest struct {
 	Condition   string   `json:"condition"`
 	ImageURL    string   `json:"image_url"`
 	Images      []string `json:"images"`
+
+	// Optional pickup location; without it the seller's location is used
+	Latitude       *float64 `json:"latitude"`
+	Longitude      *float64 `json:"longitude"`
+	AreaName       string   `json:"area_name"`
+	PrivacyRadiusM int      `json:"privacy_radius_m"`
+}
+
+const (
+	maxAreaNameLength = 100
+	maxPrivacyRadiusM = 5000
+)
+
+// sellerColumns are loaded with the seller so products without a pickup location
+// can fall back to it. presentLocation clears the seller's coordinates again.
+const sellerColumns = "id, username, full_name, image_url, latitude, longitude, hide_exact_location, city, province"
+
+// validatePickupLocation checks the pickup fields of a create/update request
+func validatePickupLocation(req *CreateProductRequest) map[string]string {
+	fieldErrors := map[string]string{}
+	validateCoordinates(req.Latitude, req.Longitude, fieldErrors)
+
+	req.AreaName = strings.TrimSpace(req.AreaName)
+	if len(req.AreaName) > maxAreaNameLength {
+		fieldErrors["area_name"] = fmt.Sprintf("Must be at most %d characters", maxAreaNameLength)
+	}
+	if req.PrivacyRadiusM < 0 || req.PrivacyRadiusM > maxPrivacyRadiusM {
+		fieldErrors["privacy_radius_m"] = fmt.Sprintf("Must be between 0 and %d", maxPrivacyRadiusM)
+	}
+	return fieldErrors
+}
+
+// viewerID returns the signed-in user on routes with optional auth, or 0
+func viewerID(c *fiber.Ctx) uint {
+	id, _ := c.Locals("user_id").(uint)
+	return id
+}
+
+// locateArea sets City/Province from the pickup point, or copies the seller's
+// for products that use the seller's location
+func (h *ProductHandler) locateArea(product *models.Product, seller *models.User) {
+	if product.Latitude == nil || product.Longitude == nil {
+		product.City, product.Province = seller.City, seller.Province
+		return
+	}
+	place, _ := h.Geo.Reverse(*product.Latitude, *product.Longitude)
+	product.City, product.Province = place.City, place.Province
+}
+
+// presentLocation sets the location a viewer may see: the pickup point, or the
+// seller's location when the product has none. Anyone but the owner gets the
+// coordinates fuzzed to the privacy radius (at least HiddenLocationRadiusM when the
+// seller hides their exact location). The seller's own coordinates are cleared.
+func presentLocation(product *models.Product, viewerID uint) {
+	seller := &product.Seller
+	if product.Latitude != nil && product.Longitude != nil {
+		product.LocationSource = "pickup"
+	} else if utils.HasLocation(seller.Latitude, seller.Longitude) {
+		lat, lng := seller.Latitude, seller.Longitude
+		product.Latitude, product.Longitude = &lat, &lng
+		product.LocationSource = "seller"
+	} else {
+		product.Latitude, product.Longitude = nil, nil
+	}
+
+	if product.Latitude != nil && product.SellerID != viewerID {
+		radius := product.PrivacyRadiusM
+		if seller.HideExactLocation && radius < utils.HiddenLocationRadiusM {
+			radius = utils.HiddenLocationRadiusM
+		}
+		if radius > 0 {
+			lat, lng := utils.FuzzCoordinates(*product.Latitude, *product.Longitude, radius)
+			product.Latitude, product.Longitude = &lat, &lng
+			product.LocationApproximate = true
+		}
+	}
+
+	seller.Latitude, seller.Longitude = 0, 0
 }
 
 // CreateProduct - POST /api/products
@@ -34,6 +124,13 @@ func (h *ProductHandler) CreateProduct(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
 	}
 
+	if fieldErrors := validatePickupLocation(&req); len(fieldErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"fields": fieldErrors,
+		})
+	}
+
 	userID := c.Locals("user_id").(uint)
 
 	product := models.Product{
@@ -46,27 +143,77 @@ func (h *ProductHandler) CreateProduct(c *fiber.Ctx) error {
 		ImageURL:    req.ImageURL,
 		Images:      req.Images,
 		Status:      "available",
+
+		Latitude:       req.Latitude,
+		Longitude:      req.Longitude,
+		AreaName:       req.AreaName,
+		PrivacyRadiusM: req.PrivacyRadiusM,
 	}
 
+	var seller models.User
+	h.DB.Select(sellerColumns).First(&seller, userID)
+	h.locateArea(&product, &seller)
+
 	if err := h.DB.Create(&product).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create product"})
 	}
 
+	product.Seller = seller
+	// Followers get their own presentation of the stored row, taken before the
+	// owner's fills in the seller fallback
+	go h.notifyFollowers(product)
+	presentLocation(&product, userID)
+
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Product created", "data": product})
 }
 
+// notifyFollowers sends a "new_product" event to the seller's followers that are online
+func (h *ProductHandler) notifyFollowers(product models.Product) {
+	var followerIDs []uint
+	if err := h.DB.Model(&models.Follow{}).Where("following_id = ?", product.SellerID).
+		Pluck("follower_id", &followerIDs).Error; err != nil {
+		log.Printf("Failed to load followers of user %d: %v", product.SellerID, err)
+		return
+	}
+
+	// Followers are not the owner, so they get the fuzzed location
+	presentLocation(&product, 0)
+	eventJSON, _ := json.Marshal(map[string]interface{}{
+		"type":      "new_product",
+		"seller_id": product.SellerID,
+		"product":   product,
+	})
+
+	for _, followerID := range followerIDs {
+		if h.Hub.IsUserOnline(followerID) {
+			h.Hub.SendToUser(followerID, eventJSON)
+		}
+	}
+}
+
 // GetAllProducts - GET /api/products
 func (h *ProductHandler) GetAllProducts(c *fiber.Ctx) error {
+	viewer := viewerID(c)
+
 	var products []models.Product
 	query := h.DB.Preload("Seller", func(db *gorm.DB) *gorm.DB {
-		return db.Select("id, username, full_name, image_url")
-	}).Where("status = ?", "available")
+		return db.Select(sellerColumns)
+	}).Where("status = ? AND hidden_at IS NULL", "available").
+		Scopes(utils.ExcludeBlocked(viewer, "seller_id"))
 
 	// Filter by Category
 	if category := c.Query("category"); category != "" {
 		query = query.Where("category = ?", category)
 	}
 
+	// Filter by Area (filled from the gazetteer)
+	if city := c.Query("city"); city != "" {
+		query = query.Where("city = ?", city)
+	}
+	if province := c.Query("province"); province != "" {
+		query = query.Where("province = ?", province)
+	}
+
 	// Search by Title
 	if q := c.Query("q"); q != "" {
 		query = query.Where("title LIKE ?", "%"+q+"%")
@@ -79,20 +226,135 @@ func (h *ProductHandler) GetAllProducts(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch products"})
 	}
 
+	for i := range products {
+		presentLocation(&products[i], viewer)
+	}
+
 	return c.JSON(fiber.Map{"data": products})
 }
 
+// NearbyProduct is a product with its distance from the search point, measured
+// to the location shown in the response (so fuzzed locations give fuzzed distances)
+type NearbyProduct struct {
+	models.Product
+	DistanceKm float64 `json:"distance_km"`
+}
+
+const (
+	defaultNearbyRadiusKm = 10
+	maxNearbyRadiusKm     = 100
+	maxNearbyResults      = 100
+	maxNearbyCandidates   = 4 * maxNearbyResults // Rows loaded before the exact radius check and fuzzing
+)
+
+// nearbyDistanceSQL orders products by an approximate (equirectangular) squared
+// distance, so only the nearest candidates are loaded. Arguments: lat, lat, lng,
+// cos(lat), lng, cos(lat).
+const nearbyDistanceSQL = "(COALESCE(products.latitude, users.latitude) - ?) * (COALESCE(products.latitude, users.latitude) - ?) + " +
+	"(COALESCE(products.longitude, users.longitude) - ?) * ? * (COALESCE(products.longitude, users.longitude) - ?) * ?"
+
+// GetNearbyProducts - GET /api/products/nearby?lat=&lng=&radius_km=
+// Uses the pickup location, or the seller's for products without one. The bounding
+// box prefilter lets MySQL use the location indexes and only the nearest
+// candidates are loaded; the exact radius check and sorting happen here.
+func (h *ProductHandler) GetNearbyProducts(c *fiber.Ctx) error {
+	lat, latErr := strconv.ParseFloat(c.Query("lat"), 64)
+	lng, lngErr := strconv.ParseFloat(c.Query("lng"), 64)
+	if latErr != nil || lngErr != nil || math.IsNaN(lat) || math.IsNaN(lng) ||
+		lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Query parameters 'lat' and 'lng' must be valid coordinates"})
+	}
+
+	radiusKm := float64(defaultNearbyRadiusKm)
+	if raw := c.Query("radius_km"); raw != "" {
+		var err error
+		radiusKm, err = strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(radiusKm) || radiusKm <= 0 || radiusKm > maxNearbyRadiusKm {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "radius_km must be between 0 and 100"})
+		}
+	}
+
+	// Widen the box by the largest fuzzing so fuzzed locations just inside the radius are not missed
+	minLat, maxLat, minLng, maxLng := utils.BoundingBox(lat, lng, radiusKm+float64(maxPrivacyRadiusM)/1000)
+
+	var products []models.Product
+	query := h.DB.Preload("Seller", func(db *gorm.DB) *gorm.DB {
+		return db.Select(sellerColumns)
+	}).
+		Joins("JOIN users ON users.id = products.seller_id AND users.deleted_at IS NULL").
+		Where("products.status = ? AND products.hidden_at IS NULL", "available").
+		Scopes(utils.ExcludeBlocked(viewerID(c), "products.seller_id")).
+		Where(h.DB.
+			Where("products.latitude BETWEEN ? AND ? AND products.longitude BETWEEN ? AND ?", minLat, maxLat, minLng, maxLng).
+			Or("products.latitude IS NULL AND users.latitude BETWEEN ? AND ? AND users.longitude BETWEEN ? AND ? AND NOT (users.latitude = 0 AND users.longitude = 0)",
+				minLat, maxLat, minLng, maxLng))
+
+	if category := c.Query("category"); category != "" {
+		query = query.Where("products.category = ?", category)
+	}
+
+	cosLat := math.Cos(lat * math.Pi / 180)
+	query = query.Clauses(clause.OrderBy{Expression: clause.Expr{
+		SQL:                nearbyDistanceSQL,
+		Vars:               []interface{}{lat, lat, lng, cosLat, lng, cosLat},
+		WithoutParentheses: true,
+	}}).Limit(maxNearbyCandidates)
+
+	if err := query.Find(&products).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch products"})
+	}
+
+	results := make([]NearbyProduct, 0, len(products))
+	for _, product := range products {
+		presentLocation(&product, viewerID(c))
+		if product.Latitude == nil {
+			continue
+		}
+
+		distance := utils.HaversineKm(lat, lng, *product.Latitude, *product.Longitude)
+		if distance > radiusKm {
+			continue
+		}
+		results = append(results, NearbyProduct{
+			Product:    product,
+			DistanceKm: math.Round(distance*100) / 100,
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].DistanceKm < results[j].DistanceKm
+	})
+	if len(results) > maxNearbyResults {
+		results = results[:maxNearbyResults]
+	}
+
+	return c.JSON(fiber.Map{"data": results})
+}
+
 // GetProduct - GET /api/products/:id
 func (h *ProductHandler) GetProduct(c *fiber.Ctx) error {
 	id, _ := strconv.Atoi(c.Params("id"))
 	var product models.Product
 
 	if err := h.DB.Preload("Seller", func(db *gorm.DB) *gorm.DB {
-		return db.Select("id, username, full_name, image_url, email") // Include email for contact/search
+		return db.Select(sellerColumns + ", email") // Include email for contact/search
 	}).First(&product, id).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Product not found"})
 	}
 
+	viewer := viewerID(c)
+	if viewer != 0 && utils.IsBlockedBetween(h.DB, viewer, product.SellerID) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Product not found"})
+	}
+
+	// Hidden products stay visible to the seller and to moderators
+	if product.HiddenAt != nil && product.SellerID != viewer &&
+		!utils.Can(c, utils.PermManageAnyProduct) && !utils.Can(c, utils.PermModerateReports) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Product not found"})
+	}
+
+	presentLocation(&product, viewer)
+
 	return c.JSON(fiber.Map{"data": product})
 }
 
@@ -114,9 +376,8 @@ func (h *ProductHandler) DeleteProduct(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Product not found"})
 	}
 
-	// Check ownership
-	if product.SellerID != userID {
-		// Assuming no admin role check for now, just ownership
+	// Check ownership (moderators and admins may delete any product)
+	if product.SellerID != userID && !utils.Can(c, utils.PermManageAnyProduct) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Not authorized"})
 	}
 
@@ -143,11 +404,15 @@ func (h *ProductHandler) GetMyProducts(c *fiber.Ctx) error {
 	var products []models.Product
 
 	if err := h.DB.Preload("Seller", func(db *gorm.DB) *gorm.DB {
-		return db.Select("id, username, full_name, image_url")
+		return db.Select(sellerColumns)
 	}).Where("seller_id = ? AND status = ?", userID, "available").Order("created_at desc").Find(&products).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch products"})
 	}
 
+	for i := range products {
+		presentLocation(&products[i], userID)
+	}
+
 	return c.JSON(fiber.Map{"data": products})
 }
 
@@ -169,8 +434,8 @@ func (h *ProductHandler) UpdateProduct(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Product not found"})
 	}
 
-	// Check ownership
-	if product.SellerID != userID {
+	// Check ownership (moderators and admins may update any product)
+	if product.SellerID != userID && !utils.Can(c, utils.PermManageAnyProduct) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Not authorized"})
 	}
 
@@ -178,6 +443,12 @@ func (h *ProductHandler) UpdateProduct(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
 	}
+	if fieldErrors := validatePickupLocation(&req); len(fieldErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"fields": fieldErrors,
+		})
+	}
 
 	// Update fields
 	product.Title = req.Title
@@ -187,10 +458,21 @@ func (h *ProductHandler) UpdateProduct(c *fiber.Ctx) error {
 	product.Condition = req.Condition
 	product.ImageURL = req.ImageURL
 	product.Images = req.Images
+	product.Latitude = req.Latitude
+	product.Longitude = req.Longitude
+	product.AreaName = req.AreaName
+	product.PrivacyRadiusM = req.PrivacyRadiusM
+
+	var seller models.User
+	h.DB.Select(sellerColumns).First(&seller, product.SellerID)
+	h.locateArea(&product, &seller)
 
 	if err := h.DB.Save(&product).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update product"})
 	}
 
+	product.Seller = seller
+	presentLocation(&product, userID)
+
 	return c.JSON(fiber.Map{"message": "Product updated", "data": product})
 }
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"testing"
+
+	"meetup_backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestGetNearbyProductsReturnsNearestFirst(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.Product{})
+
+	seller := models.User{Username: "seller", Email: "seller@example.com"}
+	db.Create(&seller)
+
+	// More products than are ever loaded, the farther ones created first
+	const count = maxNearbyCandidates + 50
+	products := make([]models.Product, 0, count)
+	for i := count - 1; i >= 0; i-- {
+		lat, lng := -6.2+float64(i)*0.0001, 106.8
+		products = append(products, models.Product{SellerID: seller.ID, Title: "item", Price: 1, Latitude: &lat, Longitude: &lng})
+	}
+	if err := db.CreateInBatches(&products, 100).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewProductHandler(db, nil, nil)
+	app := fiber.New()
+	app.Get("/nearby", h.GetNearbyProducts)
+
+	status, body := doJSON(t, app, "GET", "/nearby?lat=-6.2&lng=106.8&radius_km=50", nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("got %d: %v", status, body)
+	}
+	results, _ := body["data"].([]interface{})
+	if len(results) != maxNearbyResults {
+		t.Fatalf("got %d results, want %d", len(results), maxNearbyResults)
+	}
+
+	last := -1.0
+	for _, r := range results {
+		distance := r.(map[string]interface{})["distance_km"].(float64)
+		if distance < last {
+			t.Fatalf("results not sorted by distance: %v after %v", distance, last)
+		}
+		last = distance
+	}
+	// The 100th nearest product is about 1.1 km away
+	if last > 1.2 {
+		t.Fatalf("farthest result is %v km away, want the nearest products", last)
+	}
+}
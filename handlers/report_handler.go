@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"meetup_backend/config"
+	"meetup_backend/models"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type ReportHandler struct {
+	DB     *gorm.DB
+	Config *config.Config
+}
+
+func NewReportHandler(db *gorm.DB, cfg *config.Config) *ReportHandler {
+	return &ReportHandler{DB: db, Config: cfg}
+}
+
+// CreateReportRequest defines the payload for reporting a user, product or message
+type CreateReportRequest struct {
+	Reason  string `json:"reason"`
+	Details string `json:"details"`
+}
+
+// ReportReasons lists the accepted reason categories
+var ReportReasons = []string{"scam", "prohibited_item", "harassment", "spam", "fake_profile", "inappropriate", "other"}
+
+const maxReportDetailsLength = 1000
+
+// ReportUser - POST /api/users/:id/report
+func (h *ReportHandler) ReportUser(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	if uint(id) == userID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot report yourself"})
+	}
+
+	var user models.User
+	if err := h.DB.Select("id").First(&user, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	return h.createReport(c, models.Report{
+		TargetType:     models.ReportTargetUser,
+		TargetID:       user.ID,
+		ReportedUserID: user.ID,
+	})
+}
+
+// ReportProduct - POST /api/products/:id/report
+// Hides the product from listings once ReportHideThreshold users reported it
+func (h *ReportHandler) ReportProduct(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid product ID"})
+	}
+
+	var product models.Product
+	if err := h.DB.First(&product, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Product not found"})
+	}
+	if product.SellerID == userID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot report your own product"})
+	}
+
+	return h.createReport(c, models.Report{
+		TargetType:     models.ReportTargetProduct,
+		TargetID:       product.ID,
+		ReportedUserID: product.SellerID,
+	})
+}
+
+// ReportMessage - POST /api/chat/messages/:id/report
+// Only participants of the room can report a message. Read messages are hidden
+// from the chat but stay reportable for MessageReportWindow; the content is copied
+// into the report since the message is purged afterwards.
+func (h *ReportHandler) ReportMessage(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid message ID"})
+	}
+
+	var message models.Message
+	if err := h.DB.Unscoped().
+		Where("deleted_at IS NULL OR deleted_at > ?", time.Now().Add(-h.Config.MessageReportWindow)).
+		First(&message, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Message not found"})
+	}
+
+	var participants int64
+	h.DB.Unscoped().Model(&models.ChatParticipant{}).
+		Where("chat_room_id = ? AND user_id = ?", message.ChatRoomID, userID).
+		Count(&participants)
+	if participants == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Message not found"})
+	}
+	if message.SenderID == userID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot report your own message"})
+	}
+
+	return h.createReport(c, models.Report{
+		TargetType:      models.ReportTargetMessage,
+		TargetID:        message.ID,
+		ReportedUserID:  message.SenderID,
+		MessageSnapshot: message.Content,
+	})
+}
+
+// createReport validates the body and stores the report for the target
+func (h *ReportHandler) createReport(c *fiber.Ctx, report models.Report) error {
+	var req CreateReportRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	if !isReportReason(req.Reason) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid reason",
+			"reasons": ReportReasons,
+		})
+	}
+	req.Details = strings.TrimSpace(req.Details)
+	if len(req.Details) > maxReportDetailsLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("Details must be at most %d characters", maxReportDetailsLength)})
+	}
+
+	report.ReporterID = c.Locals("user_id").(uint)
+	report.Reason = req.Reason
+	report.Details = req.Details
+	report.Status = models.ReportStatusOpen
+
+	var existing int64
+	h.DB.Model(&models.Report{}).
+		Where("reporter_id = ? AND target_type = ? AND target_id = ?", report.ReporterID, report.TargetType, report.TargetID).
+		Count(&existing)
+	if existing > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "You have already reported this"})
+	}
+
+	if err := h.DB.Create(&report).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create report"})
+	}
+
+	if report.TargetType == models.ReportTargetProduct {
+		h.hideIfOverThreshold(report.TargetID)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Report submitted. Our moderators will review it.",
+		"data":    report,
+	})
+}
+
+// hideIfOverThreshold hides a product once enough users have open reports on it.
+// Moderators unhide it by dismissing the reports.
+func (h *ReportHandler) hideIfOverThreshold(productID uint) {
+	if h.Config.ReportHideThreshold <= 0 {
+		return
+	}
+
+	var reporters int64
+	h.DB.Model(&models.Report{}).
+		Where("target_type = ? AND target_id = ? AND status IN ?", models.ReportTargetProduct, productID,
+			[]string{models.ReportStatusOpen, models.ReportStatusInReview}).
+		Distinct("reporter_id").
+		Count(&reporters)
+	if reporters < int64(h.Config.ReportHideThreshold) {
+		return
+	}
+
+	result := h.DB.Model(&models.Product{}).
+		Where("id = ? AND hidden_at IS NULL", productID).
+		Updates(map[string]interface{}{"hidden_at": time.Now(), "hidden_reason": "reports"})
+	if result.Error != nil {
+		log.Printf("Failed to hide reported product %d: %v", productID, result.Error)
+	} else if result.RowsAffected > 0 {
+		log.Printf("Product %d hidden after %d reports", productID, reporters)
+	}
+}
+
+func isReportReason(reason string) bool {
+	for _, r := range ReportReasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"meetup_backend/config"
+	"meetup_backend/internal/chat"
+	"meetup_backend/internal/ws"
+	"meetup_backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestReportMessageAfterItWasRead(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.ChatRoom{}, &models.ChatParticipant{}, &models.Message{}, &models.Report{})
+
+	sender := models.User{Username: "sender", Email: "sender@example.com"}
+	recipient := models.User{Username: "recipient", Email: "recipient@example.com"}
+	db.Create(&sender)
+	db.Create(&recipient)
+	room := models.ChatRoom{Participants: []models.ChatParticipant{{UserID: sender.ID}, {UserID: recipient.ID}}}
+	db.Create(&room)
+	message := models.Message{ChatRoomID: room.ID, SenderID: sender.ID, Content: "harassing message"}
+	db.Create(&message)
+
+	cfg := &config.Config{MessageReportWindow: 24 * time.Hour}
+	chats := NewChatHandler(ws.NewHub(), db, nil)
+	reports := NewReportHandler(db, cfg)
+	app := fiber.New()
+	app.Get("/chats/:roomID/messages", asUser(recipient.ID), chats.GetChatMessages)
+	app.Post("/messages/:id/report", asUser(recipient.ID), reports.ReportMessage)
+
+	// Reading the message hides it from the chat...
+	doJSON(t, app, "GET", fmt.Sprintf("/chats/%d/messages", room.ID), nil)
+	_, body := doJSON(t, app, "GET", fmt.Sprintf("/chats/%d/messages", room.ID), nil)
+	if messages, _ := body["messages"].([]interface{}); len(messages) != 0 {
+		t.Fatalf("read message is still in the chat: %v", messages)
+	}
+
+	// ...but it can still be reported
+	status, body := doJSON(t, app, "POST", fmt.Sprintf("/messages/%d/report", message.ID), CreateReportRequest{Reason: "harassment"})
+	if status != fiber.StatusCreated {
+		t.Fatalf("report returned %d: %v", status, body)
+	}
+	var report models.Report
+	db.First(&report)
+	if report.MessageSnapshot != "harassing message" || report.ReportedUserID != sender.ID {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	// Once the report window has passed the message is purged
+	db.Unscoped().Model(&models.Message{}).Where("id = ?", message.ID).Update("deleted_at", time.Now().Add(-48*time.Hour))
+	if n, err := chat.NewMessagePurger(db, cfg.MessageReportWindow).PurgeExpired(); err != nil || n != 1 {
+		t.Fatalf("PurgeExpired() = %d, %v; want 1, nil", n, err)
+	}
+	var left int64
+	db.Unscoped().Model(&models.Message{}).Count(&left)
+	if left != 0 {
+		t.Fatalf("%d message(s) left after purge", left)
+	}
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"meetup_backend/config"
+	"meetup_backend/models"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReviewHandler struct {
+	DB     *gorm.DB
+	Config *config.Config
+}
+
+func NewReviewHandler(db *gorm.DB, cfg *config.Config) *ReviewHandler {
+	return &ReviewHandler{DB: db, Config: cfg}
+}
+
+// CreateReviewRequest defines the payload for reviewing a user.
+// MeetupID empty picks the latest meetup with that user that can still be reviewed.
+type CreateReviewRequest struct {
+	MeetupID uint   `json:"meetup_id"`
+	Rating   int    `json:"rating"`
+	Comment  string `json:"comment"`
+}
+
+// RatingSummary is the average of the visible reviews a user received
+type RatingSummary struct {
+	Average float64 `json:"average"`
+	Count   int64   `json:"count"`
+}
+
+// ReviewEntry is a review in a user's public review list
+type ReviewEntry struct {
+	ID        uint               `json:"id"`
+	MeetupID  uint               `json:"meetup_id"`
+	Rating    int                `json:"rating"`
+	Comment   string             `json:"comment"`
+	Reviewer  models.UserSummary `json:"reviewer"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+const maxReviewCommentLength = 1000
+
+// CreateReview - POST /api/users/:id/reviews
+// Only allowed for participants of a confirmed meetup with the user, once per
+// meetup and within REVIEW_WINDOW of the confirmation
+func (h *ReviewHandler) CreateReview(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	targetID, err := c.ParamsInt("id")
+	if err != nil || targetID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	if uint(targetID) == userID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot review yourself"})
+	}
+
+	var req CreateReviewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	if req.Rating < 1 || req.Rating > 5 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Rating must be between 1 and 5"})
+	}
+	req.Comment = strings.TrimSpace(req.Comment)
+	if len(req.Comment) > maxReviewCommentLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("Comment must be at most %d characters", maxReviewCommentLength)})
+	}
+
+	var target models.User
+	if err := h.DB.Select("id").First(&target, targetID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	// Meetups both users took part in, newest first
+	var meetups []models.Meetup
+	query := h.DB.Model(&models.Meetup{}).
+		Joins("JOIN meetup_participants mine ON mine.meetup_id = meetups.id AND mine.user_id = ?", userID).
+		Joins("JOIN meetup_participants theirs ON theirs.meetup_id = meetups.id AND theirs.user_id = ?", target.ID).
+		Order("meetups.confirmed_at desc")
+	if req.MeetupID != 0 {
+		query = query.Where("meetups.id = ?", req.MeetupID)
+	}
+	if err := query.Find(&meetups).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create review"})
+	}
+	if len(meetups) == 0 {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You can only review users you have a confirmed meetup with"})
+	}
+
+	var reviewedIDs []uint
+	h.DB.Model(&models.Review{}).
+		Where("reviewer_id = ? AND reviewee_id = ?", userID, target.ID).
+		Pluck("meetup_id", &reviewedIDs)
+	reviewed := make(map[uint]bool, len(reviewedIDs))
+	for _, id := range reviewedIDs {
+		reviewed[id] = true
+	}
+
+	now := time.Now()
+	var meetup *models.Meetup
+	windowClosed := false
+	for i := range meetups {
+		if reviewed[meetups[i].ID] {
+			continue
+		}
+		if now.After(h.reviewDeadline(&meetups[i])) {
+			windowClosed = true
+			continue
+		}
+		meetup = &meetups[i]
+		break
+	}
+	if meetup == nil {
+		if windowClosed {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "The review window for this meetup has closed"})
+		}
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "You have already reviewed this user for this meetup"})
+	}
+
+	review := models.Review{
+		MeetupID:   meetup.ID,
+		ReviewerID: userID,
+		RevieweeID: target.ID,
+		Rating:     req.Rating,
+		Comment:    req.Comment,
+		VisibleAt:  h.reviewDeadline(meetup),
+	}
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the meetup so two reviews submitted at once both see each other
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Meetup{}, meetup.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&review).Error; err != nil {
+			return err
+		}
+
+		// Both sides reviewed: reveal both now
+		result := tx.Model(&models.Review{}).
+			Where("meetup_id = ? AND reviewer_id = ? AND reviewee_id = ?", meetup.ID, target.ID, userID).
+			Update("visible_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			review.VisibleAt = now
+			return tx.Model(&review).Update("visible_at", now).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create review"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Review submitted",
+		"data":    review,
+	})
+}
+
+// GetUserReviews - GET /api/users/:id/reviews?cursor=&limit= (Public)
+// Only reviews that are already visible, newest first
+func (h *ReviewHandler) GetUserReviews(c *fiber.Ctx) error {
+	targetID, err := c.ParamsInt("id")
+	if err != nil || targetID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	cursor, limit, err := pageParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var target models.User
+	if err := h.DB.Select("id").First(&target, targetID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	var reviews []models.Review
+	query := h.DB.Preload("Reviewer", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped().Select("id, username, full_name, image_url")
+	}).
+		Where("reviewee_id = ? AND visible_at <= ?", target.ID, time.Now()).
+		Order("id desc").
+		Limit(limit)
+	if cursor != 0 {
+		query = query.Where("id < ?", cursor)
+	}
+	if err := query.Find(&reviews).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch reviews"})
+	}
+
+	entries := make([]ReviewEntry, 0, len(reviews))
+	for _, r := range reviews {
+		entries = append(entries, ReviewEntry{
+			ID:        r.ID,
+			MeetupID:  r.MeetupID,
+			Rating:    r.Rating,
+			Comment:   r.Comment,
+			Reviewer:  newUserSummary(r.Reviewer),
+			CreatedAt: r.CreatedAt,
+		})
+	}
+
+	var nextCursor *uint
+	if len(reviews) == limit {
+		nextCursor = &reviews[len(reviews)-1].ID
+	}
+
+	return c.JSON(fiber.Map{
+		"data":        entries,
+		"rating":      ratingSummary(h.DB, target.ID),
+		"next_cursor": nextCursor,
+	})
+}
+
+func newUserSummary(user *models.User) models.UserSummary {
+	if user == nil {
+		return models.UserSummary{}
+	}
+	return user.Summary()
+}
+
+// reviewDeadline is when reviews for the meetup close and hidden ones are revealed
+func (h *ReviewHandler) reviewDeadline(meetup *models.Meetup) time.Time {
+	return meetup.ConfirmedAt.Add(h.Config.ReviewWindow)
+}
+
+// ratingSummary averages the visible reviews of a user, rounded to one decimal
+func ratingSummary(db *gorm.DB, userID uint) RatingSummary {
+	var row struct {
+		Average float64
+		Count   int64
+	}
+	db.Model(&models.Review{}).
+		Select("COALESCE(AVG(rating), 0) AS average, COUNT(*) AS count").
+		Where("reviewee_id = ? AND visible_at <= ?", userID, time.Now()).
+		Scan(&row)
+
+	return RatingSummary{Average: math.Round(row.Average*10) / 10, Count: row.Count}
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"meetup_backend/internal/payment"
+	"meetup_backend/internal/topup"
+	"meetup_backend/models"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type TopUpHandler struct {
+	DB    *gorm.DB
+	TopUp *topup.Service
+}
+
+func NewTopUpHandler(db *gorm.DB, topUps *topup.Service) *TopUpHandler {
+	return &TopUpHandler{DB: db, TopUp: topUps}
+}
+
+// CheckoutRequest defines the payload for buying a point package
+type CheckoutRequest struct {
+	PackageID uint `json:"package_id"`
+}
+
+// SimulatePaymentRequest defines the payload for the fake provider's payment page
+type SimulatePaymentRequest struct {
+	Status string `json:"status"` // paid, failed, expired, refunded
+}
+
+// GetPackages - GET /api/points/packages (Public)
+func (h *TopUpHandler) GetPackages(c *fiber.Ctx) error {
+	packages := []models.PointPackage{}
+	if err := h.DB.Where("active = ?", true).Order("points asc").Find(&packages).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch point packages"})
+	}
+	return c.JSON(fiber.Map{"data": packages})
+}
+
+// Checkout - POST /api/points/checkout
+// Creates a pending order; points are credited once the provider confirms the payment
+func (h *TopUpHandler) Checkout(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req CheckoutRequest
+	if err := c.BodyParser(&req); err != nil || req.PackageID == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "package_id is required"})
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	order, err := h.TopUp.Checkout(&user, req.PackageID)
+	switch {
+	case errors.Is(err, topup.ErrPackageNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Point package not found"})
+	case errors.Is(err, topup.ErrTooManyPending):
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "You have too many unpaid orders. Pay or wait for one to expire."})
+	case err != nil:
+		log.Printf("Checkout failed for user %d: %v", userID, err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Could not start the payment, please try again"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Order created. Complete the payment to receive your points.",
+		"data":    order,
+	})
+}
+
+// ListOrders - GET /api/points/orders?cursor=&limit=
+func (h *TopUpHandler) ListOrders(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	cursor, limit, err := pageParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	query := h.DB.Where("user_id = ?", userID).Order("id desc").Limit(limit)
+	if cursor != 0 {
+		query = query.Where("id < ?", cursor)
+	}
+	orders := []models.PaymentOrder{}
+	if err := query.Find(&orders).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch orders"})
+	}
+
+	var nextCursor *uint
+	if len(orders) == limit {
+		nextCursor = &orders[len(orders)-1].ID
+	}
+
+	return c.JSON(fiber.Map{"data": orders, "next_cursor": nextCursor})
+}
+
+// GetOrder - GET /api/points/orders/:id
+func (h *TopUpHandler) GetOrder(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid order ID"})
+	}
+
+	var order models.PaymentOrder
+	if err := h.DB.Where("id = ? AND user_id = ?", id, userID).First(&order).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Order not found"})
+	}
+
+	return c.JSON(fiber.Map{"data": order})
+}
+
+// Webhook - POST /api/payments/webhook/:provider
+// Called by the payment provider. Only signed events change orders.
+func (h *TopUpHandler) Webhook(c *fiber.Ctx) error {
+	if c.Params("provider") != h.TopUp.Provider.Name() {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Unknown payment provider"})
+	}
+
+	header := http.Header{}
+	for key, values := range c.GetReqHeaders() {
+		for _, value := range values {
+			header.Add(key, value)
+		}
+	}
+	// The body is copied because fasthttp reuses the buffer after the handler returns
+	return h.handleWebhook(c, header, append([]byte(nil), c.Body()...))
+}
+
+// SimulatePayment - POST /api/payments/fake/orders/:reference
+// Development only: the fake provider's payment page. Sends the signed webhook
+// the provider would send for the chosen outcome.
+func (h *TopUpHandler) SimulatePayment(c *fiber.Ctx) error {
+	fake, ok := h.TopUp.Provider.(*payment.FakeProvider)
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Not available"})
+	}
+
+	var req SimulatePaymentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	var order models.PaymentOrder
+	if err := h.DB.Where("reference = ? AND user_id = ?", c.Params("reference"), c.Locals("user_id").(uint)).First(&order).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Order not found"})
+	}
+
+	header, body, err := fake.Simulate(order.Reference, order.ProviderReference, req.Status, order.Amount, order.Currency)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not simulate payment"})
+	}
+	return h.handleWebhook(c, header, body)
+}
+
+func (h *TopUpHandler) handleWebhook(c *fiber.Ctx, header http.Header, body []byte) error {
+	event, err := h.TopUp.Provider.ParseWebhook(header, body)
+	if errors.Is(err, payment.ErrInvalidSignature) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid signature"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	order, err := h.TopUp.HandleEvent(event, body)
+	switch {
+	case errors.Is(err, topup.ErrUnknownOrder):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Order not found"})
+	case errors.Is(err, topup.ErrUnsupportedStatus):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unsupported payment status"})
+	case err != nil:
+		// Non-2xx makes the provider deliver the event again
+		log.Printf("Failed to process payment event %s: %v", event.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not process event"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Event processed", "data": order})
+}
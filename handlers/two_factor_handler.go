@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"meetup_backend/config"
+	"meetup_backend/models"
+	"meetup_backend/utils"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type TwoFactorHandler struct {
+	DB       *gorm.DB
+	Config   *config.Config
+	Throttle *utils.LoginThrottle // Shared with login, so codes cannot be guessed here instead
+}
+
+func NewTwoFactorHandler(db *gorm.DB, cfg *config.Config) *TwoFactorHandler {
+	return &TwoFactorHandler{DB: db, Config: cfg, Throttle: newLoginThrottle(db, cfg)}
+}
+
+// TwoFactorCodeRequest carries a code from the authenticator app
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// Setup - POST /api/auth/2fa/setup
+// Generates a new secret. 2FA stays disabled until the secret is confirmed with a code.
+func (h *TwoFactorHandler) Setup(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var user models.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if user.TOTPEnabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Two-factor authentication is already enabled"})
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not generate secret"})
+	}
+
+	if err := h.DB.Model(&user).Updates(map[string]interface{}{
+		"totp_secret":    secret,
+		"totp_last_step": 0,
+	}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not start setup"})
+	}
+
+	return c.JSON(fiber.Map{
+		"secret":      secret,
+		"otpauth_uri": utils.TOTPAuthURI(h.Config.TOTPIssuer, user.Email, secret),
+	})
+}
+
+// Confirm - POST /api/auth/2fa/confirm
+// Enables 2FA once the user proves their app generates valid codes, and returns recovery codes
+func (h *TwoFactorHandler) Confirm(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if user.TOTPEnabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Two-factor authentication is already enabled"})
+	}
+	if user.TOTPSecret == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Call setup first"})
+	}
+
+	if !h.verifyCode(c, &user, req.Code, fiber.StatusBadRequest) {
+		return nil
+	}
+
+	if err := h.DB.Model(&user).Update("totp_enabled", true).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not enable two-factor authentication"})
+	}
+
+	codes, err := utils.GenerateRecoveryCodes(h.DB, user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not generate recovery codes"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// Disable - POST /api/auth/2fa/disable
+// Requires a current code from the authenticator app
+func (h *TwoFactorHandler) Disable(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if !user.TOTPEnabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Two-factor authentication is not enabled"})
+	}
+
+	if !h.verifyCode(c, &user, req.Code, fiber.StatusUnauthorized) {
+		return nil
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"totp_enabled":   false,
+			"totp_secret":    "",
+			"totp_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not disable two-factor authentication"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes - POST /api/auth/2fa/recovery-codes
+// Replaces all recovery codes; requires a current code from the authenticator app
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if !user.TOTPEnabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Two-factor authentication is not enabled"})
+	}
+
+	if !h.verifyCode(c, &user, req.Code, fiber.StatusUnauthorized) {
+		return nil
+	}
+
+	codes, err := utils.GenerateRecoveryCodes(h.DB, user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not generate recovery codes"})
+	}
+
+	return c.JSON(fiber.Map{"recovery_codes": codes})
+}
+
+// verifyCode checks a code from the authenticator app. Wrong codes count toward
+// the same per-account limit as the second login step, so a stolen access token
+// cannot be used to guess codes. When the code is not accepted it writes the
+// error response and returns false.
+func (h *TwoFactorHandler) verifyCode(c *fiber.Ctx, user *models.User, code string, failStatus int) bool {
+	attempt, wait, _ := h.Throttle.Reserve(user.Email, c.IP(), c.Get(fiber.HeaderUserAgent))
+	if wait > 0 {
+		seconds := int(wait.Seconds()) + 1
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+		c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error":       "Too many invalid authentication codes. Please wait before trying again.",
+			"retry_after": seconds,
+		})
+		return false
+	}
+
+	if !utils.VerifyUserTOTP(h.DB, user, code) {
+		attempt.Fail(&user.ID, "bad_2fa_code")
+		c.Status(failStatus).JSON(fiber.Map{"error": "Invalid authentication code"})
+		return false
+	}
+	attempt.Cancel()
+	return true
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"meetup_backend/config"
+	"meetup_backend/models"
+	"meetup_backend/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestDisableTwoFactorIsThrottled(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.RecoveryCode{}, &models.LoginAttempt{})
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := models.User{Username: "alice", Email: "alice@example.com", TOTPEnabled: true, TOTPSecret: secret}
+	db.Create(&user)
+
+	h := NewTwoFactorHandler(db, &config.Config{
+		LoginWindow:          15 * time.Minute,
+		LoginFreeAttempts:    3,
+		LoginMaxAttempts:     10,
+		LoginIPFreeAttempts:  20,
+		LoginIPMaxAttempts:   50,
+		LoginBackoffBase:     time.Minute,
+		LoginBackoffMax:      time.Hour,
+		LoginLockoutDuration: time.Hour,
+	})
+	app := fiber.New()
+	app.Post("/2fa/disable", asUser(user.ID), h.Disable)
+
+	for i := 0; i < 3; i++ {
+		if status, body := doJSON(t, app, "POST", "/2fa/disable", TwoFactorCodeRequest{Code: "000000"}); status != fiber.StatusUnauthorized {
+			t.Fatalf("attempt %d returned %d: %v", i+1, status, body)
+		}
+	}
+
+	// Once throttled, even the right code is not checked
+	code, err := utils.TOTPCode(secret, utils.TOTPStep(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, body := doJSON(t, app, "POST", "/2fa/disable", TwoFactorCodeRequest{Code: code}); status != fiber.StatusTooManyRequests {
+		t.Fatalf("throttled attempt returned %d: %v", status, body)
+	}
+
+	db.First(&user, user.ID)
+	if !user.TOTPEnabled {
+		t.Fatal("two-factor authentication was disabled while throttled")
+	}
+}
@@ -1,18 +1,82 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"meetup_backend/models"
+	"mime/multipart"
+	"net/http"
+	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 // UploadHandler handles file uploads
-type UploadHandler struct{}
+type UploadHandler struct {
+	DB *gorm.DB
+}
+
+func NewUploadHandler(db *gorm.DB) *UploadHandler {
+	return &UploadHandler{DB: db}
+}
+
+// Upload folders below ./uploads (served at /uploads)
+const (
+	uploadFolderProducts = "products"
+	uploadFolderAvatars  = "avatars"
+)
+
+var errInvalidImage = errors.New("only .jpg, .jpeg, and .png files are allowed")
 
-func NewUploadHandler() *UploadHandler {
-	return &UploadHandler{}
+// allowedImageTypes maps allowed extensions to the content type the file must actually have
+var allowedImageTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+}
+
+// saveUploadedImage validates an uploaded image and stores it under ./uploads/<folder>
+// with a generated name, recorded as uploaded by the current user. It returns the public URL.
+func saveUploadedImage(c *fiber.Ctx, db *gorm.DB, file *multipart.FileHeader, folder string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	contentType, ok := allowedImageTypes[ext]
+	if !ok {
+		return "", errInvalidImage
+	}
+
+	// Check the content too, not only the extension
+	f, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	head := make([]byte, 512)
+	n, _ := f.Read(head)
+	f.Close()
+	if http.DetectContentType(head[:n]) != contentType {
+		return "", errInvalidImage
+	}
+
+	dir := filepath.Join(".", "uploads", folder)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	filename := fmt.Sprintf("%d%s", time.Now().UnixNano(), ext)
+	path := filepath.Join(dir, filename)
+	if err := c.SaveFile(file, path); err != nil {
+		return "", err
+	}
+
+	url := fmt.Sprintf("/uploads/%s/%s", folder, filename)
+	if err := db.Create(&models.Upload{UserID: c.Locals("user_id").(uint), URL: url}).Error; err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return url, nil
 }
 
 // UploadImage handles image uploads and returns the file URL
@@ -25,31 +89,18 @@ func (h *UploadHandler) UploadImage(c *fiber.Ctx) error {
 		})
 	}
 
-	// Validate file type (simple check extension)
-	ext := filepath.Ext(file.Filename)
-	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
+	imageURL, err := saveUploadedImage(c, h.DB, file, uploadFolderProducts)
+	if errors.Is(err, errInvalidImage) {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Only .jpg, .jpeg, and .png files are allowed",
 		})
 	}
-
-	// Generate unique filename
-	filename := fmt.Sprintf("%d%s", time.Now().UnixNano(), ext)
-
-	// Create destination path
-	// Ensure this directory exists! We might need to create it in main.go or here
-	destination := fmt.Sprintf("./uploads/products/%s", filename)
-
-	if err := c.SaveFile(file, destination); err != nil {
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Could not save file",
 		})
 	}
 
-	// Return the public URL
-	// Assuming static files are served from /uploads
-	imageURL := fmt.Sprintf("/uploads/products/%s", filename)
-
 	return c.JSON(fiber.Map{
 		"url": imageURL,
 	})
@@ -70,21 +121,13 @@ func (h *UploadHandler) UploadMultipleImages(c *fiber.Ctx) error {
 	var urls []string
 
 	for _, file := range files {
-		// Validate file type
-		ext := filepath.Ext(file.Filename)
-		if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
-			continue // Skip invalid files or return error
+		imageURL, err := saveUploadedImage(c, h.DB, file, uploadFolderProducts)
+		if errors.Is(err, errInvalidImage) {
+			continue // Skip invalid files
 		}
-
-		// Generate unique filename
-		filename := fmt.Sprintf("%d_%s", time.Now().UnixNano(), file.Filename)
-		destination := fmt.Sprintf("./uploads/products/%s", filename)
-
-		if err := c.SaveFile(file, destination); err != nil {
+		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not save file"})
 		}
-
-		imageURL := fmt.Sprintf("/uploads/products/%s", filename)
 		urls = append(urls, imageURL)
 	}
 
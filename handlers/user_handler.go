@@ -1,18 +1,28 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"meetup_backend/internal/geo"
 	"meetup_backend/models"
+	"meetup_backend/utils"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
 )
 
 type UserHandler struct {
-	DB *gorm.DB
+	DB  *gorm.DB
+	Geo *geo.Gazetteer
 }
 
-func NewUserHandler(db *gorm.DB) *UserHandler {
-	return &UserHandler{DB: db}
+func NewUserHandler(db *gorm.DB, gazetteer *geo.Gazetteer) *UserHandler {
+	return &UserHandler{DB: db, Geo: gazetteer}
 }
 
 // SearchUsers allows searching for users by username or email
@@ -32,7 +42,8 @@ func (h *UserHandler) SearchUsers(c *fiber.Ctx) error {
 	// AND the user is NOT the current user
 	err := h.DB.Select("id, username, email, full_name, image_url").
 		Where("(username LIKE ? OR email LIKE ?) AND id != ?", "%"+query+"%", "%"+query+"%", currentUserID).
-		Limit(10). // Limit results
+		Scopes(utils.ExcludeBlocked(currentUserID.(uint), "id")). // Hide users in a block with the searcher
+		Limit(10).                                                // Limit results
 		Find(&users).Error
 
 	if err != nil {
@@ -45,3 +56,262 @@ func (h *UserHandler) SearchUsers(c *fiber.Ctx) error {
 		"data": users,
 	})
 }
+
+// UpdateProfileRequest defines the editable profile fields. Omitted fields are left unchanged.
+type UpdateProfileRequest struct {
+	FullName             *string  `json:"full_name"`
+	ImageURL             *string  `json:"image_url"`
+	Address              *string  `json:"address"`
+	Latitude             *float64 `json:"latitude"`
+	Longitude            *float64 `json:"longitude"`
+	RequireVerifiedPhone *bool    `json:"require_verified_phone"`
+	HideExactLocation    *bool    `json:"hide_exact_location"`
+}
+
+// PublicProfile is what other users can see about a user
+type PublicProfile struct {
+	ID             uint          `json:"id"`
+	Username       string        `json:"username"`
+	FullName       string        `json:"full_name"`
+	ImageURL       string        `json:"image_url"`
+	IsVerified     bool          `json:"is_verified"`
+	PhoneVerified  bool          `json:"phone_verified"`
+	ListingCount   int64         `json:"listing_count"` // Available products
+	FollowerCount  int64         `json:"follower_count"`
+	FollowingCount int64         `json:"following_count"`
+	Rating         RatingSummary `json:"rating"`        // Visible reviews from meetups
+	NoShowCount    int           `json:"no_show_count"` // Meetups they did not show up to
+	MemberSince    time.Time     `json:"member_since"`
+}
+
+const (
+	maxFullNameLength = 100
+	maxAddressLength  = 500
+	maxImageURLLength = 255
+)
+
+// GetMe - GET /api/users/me
+func (h *UserHandler) GetMe(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var user models.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	return c.JSON(fiber.Map{"data": user})
+}
+
+// UpdateMe - PATCH /api/users/me
+// Updates only the fields present in the body; every field is validated before anything is saved
+func (h *UserHandler) UpdateMe(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req UpdateProfileRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	updates := map[string]interface{}{}
+	fieldErrors := map[string]string{}
+
+	if req.FullName != nil {
+		name := strings.TrimSpace(*req.FullName)
+		if len(name) > maxFullNameLength {
+			fieldErrors["full_name"] = fmt.Sprintf("Must be at most %d characters", maxFullNameLength)
+		} else {
+			updates["full_name"] = name
+		}
+	}
+
+	if req.ImageURL != nil {
+		imageURL := strings.TrimSpace(*req.ImageURL)
+		if err := validateImageURL(imageURL); err != nil {
+			fieldErrors["image_url"] = err.Error()
+		} else if strings.HasPrefix(imageURL, "/uploads/") && !utils.OwnsUpload(h.DB, userID, imageURL) {
+			fieldErrors["image_url"] = "Must be an image you uploaded"
+		} else {
+			updates["image_url"] = imageURL
+		}
+	}
+
+	if req.Address != nil {
+		address := strings.TrimSpace(*req.Address)
+		if len(address) > maxAddressLength {
+			fieldErrors["address"] = fmt.Sprintf("Must be at most %d characters", maxAddressLength)
+		} else {
+			updates["address"] = address
+		}
+	}
+
+	if validateCoordinates(req.Latitude, req.Longitude, fieldErrors) {
+		place, _ := h.Geo.Reverse(*req.Latitude, *req.Longitude)
+		updates["latitude"] = *req.Latitude
+		updates["longitude"] = *req.Longitude
+		updates["city"] = place.City
+		updates["province"] = place.Province
+	}
+
+	if req.RequireVerifiedPhone != nil {
+		updates["require_verified_phone"] = *req.RequireVerifiedPhone
+	}
+
+	if req.HideExactLocation != nil {
+		updates["hide_exact_location"] = *req.HideExactLocation
+	}
+
+	if len(fieldErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"fields": fieldErrors,
+		})
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if len(updates) > 0 {
+		err := h.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&user).Updates(updates).Error; err != nil {
+				return err
+			}
+			if _, moved := updates["city"]; !moved {
+				return nil
+			}
+			// Products without a pickup point are located at the seller
+			return tx.Model(&models.Product{}).Where("seller_id = ? AND latitude IS NULL", userID).
+				Updates(map[string]interface{}{"city": updates["city"], "province": updates["province"]}).Error
+		})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update profile"})
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Profile updated successfully",
+		"data":    user,
+	})
+}
+
+// validateCoordinates records problems with an optional latitude/longitude pair in
+// fieldErrors and reports whether a valid pair was given
+func validateCoordinates(lat, lng *float64, fieldErrors map[string]string) bool {
+	// Coordinates only make sense as a pair
+	if (lat == nil) != (lng == nil) {
+		fieldErrors["latitude"] = "Latitude and longitude must be set together"
+		return false
+	}
+	if lat == nil {
+		return false
+	}
+	switch {
+	case math.IsNaN(*lat) || *lat < -90 || *lat > 90:
+		fieldErrors["latitude"] = "Must be between -90 and 90"
+	case math.IsNaN(*lng) || *lng < -180 || *lng > 180:
+		fieldErrors["longitude"] = "Must be between -180 and 180"
+	default:
+		return true
+	}
+	return false
+}
+
+// validateImageURL accepts our own uploads or an absolute http(s) URL. Who
+// uploaded the file is checked by the caller.
+func validateImageURL(imageURL string) error {
+	if imageURL == "" {
+		return nil
+	}
+	if len(imageURL) > maxImageURLLength {
+		return fmt.Errorf("Must be at most %d characters", maxImageURLLength)
+	}
+	if strings.HasPrefix(imageURL, "/uploads/") {
+		return nil
+	}
+	parsed, err := url.Parse(imageURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("Must be an uploaded image or an http(s) URL")
+	}
+	return nil
+}
+
+// UploadAvatar - POST /api/users/me/avatar (multipart, field "image")
+// Stores the image through the upload pipeline and sets it as the profile picture
+func (h *UserHandler) UploadAvatar(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	file, err := c.FormFile("image")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Image file is required"})
+	}
+
+	var user models.User
+	if err := h.DB.Select("id", "image_url").First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	oldImageURL := user.ImageURL
+
+	imageURL, err := saveUploadedImage(c, h.DB, file, uploadFolderAvatars)
+	if errors.Is(err, errInvalidImage) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Only .jpg, .jpeg, and .png files are allowed"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not save file"})
+	}
+
+	if err := h.DB.Model(&user).Update("image_url", imageURL).Error; err != nil {
+		utils.RemoveUserUpload(h.DB, userID, imageURL)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update profile"})
+	}
+
+	// The previous avatar is no longer referenced; files of other users are never touched
+	if strings.HasPrefix(oldImageURL, "/uploads/"+uploadFolderAvatars+"/") {
+		if err := utils.RemoveUserUpload(h.DB, userID, oldImageURL); err != nil {
+			log.Printf("Failed to remove old avatar of user %d: %v", userID, err)
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Avatar updated successfully",
+		"url":     imageURL,
+	})
+}
+
+// GetProfile - GET /api/users/:id (Public)
+// Only exposes fields that are safe to show to anyone
+func (h *UserHandler) GetProfile(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	var listingCount int64
+	h.DB.Model(&models.Product{}).Where("seller_id = ? AND status = ?", user.ID, "available").Count(&listingCount)
+
+	var followerCount, followingCount int64
+	h.DB.Model(&models.Follow{}).Where("following_id = ?", user.ID).Count(&followerCount)
+	h.DB.Model(&models.Follow{}).Where("follower_id = ?", user.ID).Count(&followingCount)
+
+	return c.JSON(fiber.Map{
+		"data": PublicProfile{
+			ID:             user.ID,
+			Username:       user.Username,
+			FullName:       user.FullName,
+			ImageURL:       user.ImageURL,
+			IsVerified:     user.IsVerified,
+			PhoneVerified:  user.PhoneVerified,
+			ListingCount:   listingCount,
+			FollowerCount:  followerCount,
+			FollowingCount: followingCount,
+			Rating:         ratingSummary(h.DB, user.ID),
+			NoShowCount:    user.NoShowCount,
+			MemberSince:    user.CreatedAt,
+		},
+	})
+}
@@ -0,0 +1,167 @@
+package account
+
+import (
+	"fmt"
+	"log"
+	"meetup_backend/models"
+	"meetup_backend/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Purger anonymises accounts whose deletion grace period has ended
+type Purger struct {
+	DB *gorm.DB
+}
+
+func NewPurger(db *gorm.DB) *Purger {
+	return &Purger{DB: db}
+}
+
+// Run purges due accounts every interval. It blocks, so start it in a goroutine.
+func (p *Purger) Run(every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		if n, err := p.PurgeDue(); err != nil {
+			log.Printf("Account purge failed: %v", err)
+		} else if n > 0 {
+			log.Printf("Purged %d deleted account(s)", n)
+		}
+		<-ticker.C
+	}
+}
+
+// PurgeDue anonymises every account scheduled for deletion before now
+func (p *Purger) PurgeDue() (int, error) {
+	var users []models.User
+	if err := p.DB.Where("deletion_scheduled_at <= ? AND anonymized_at IS NULL", time.Now()).Find(&users).Error; err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for i := range users {
+		if err := p.Purge(&users[i]); err != nil {
+			log.Printf("Failed to purge user %d: %v", users[i].ID, err)
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// Purge removes the user's personal data: the User row is anonymised and soft-deleted,
+// products are soft-deleted, chat participations and stored messages are removed,
+// credentials are dropped and the files they uploaded (avatar, product and chat
+// images) are deleted from disk.
+func (p *Purger) Purge(user *models.User) error {
+	// Only files they uploaded: image URLs on profiles and products are chosen by
+	// the client and may point at someone else's upload
+	var uploads []models.Upload
+	if err := p.DB.Where("user_id = ?", user.ID).Find(&uploads).Error; err != nil {
+		return err
+	}
+	email := user.Email
+
+	now := time.Now()
+	err := p.DB.Transaction(func(tx *gorm.DB) error {
+		// Pickup points are usually near home, so they go too
+		if err := tx.Model(&models.Product{}).Where("seller_id = ?", user.ID).Updates(map[string]interface{}{
+			"latitude":  nil,
+			"longitude": nil,
+			"area_name": "",
+			"city":      "",
+			"province":  "",
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("seller_id = ?", user.ID).Delete(&models.Product{}).Error; err != nil {
+			return err
+		}
+
+		// Chat: leave every room and delete the messages they sent
+		if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.ChatParticipant{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("sender_id = ?", user.ID).Delete(&models.Message{}).Error; err != nil {
+			return err
+		}
+
+		// Credentials and security records
+		sessionIDs := tx.Model(&models.Session{}).Select("id").Where("user_id = ?", user.ID)
+		if err := tx.Where("session_id IN (?)", sessionIDs).Delete(&models.RefreshToken{}).Error; err != nil {
+			return err
+		}
+		cleanup := []interface{}{
+			&models.Session{},
+			&models.OAuthState{},
+			&models.APIKey{},
+			&models.UserIdentity{},
+			&models.RecoveryCode{},
+			&models.PasswordReset{},
+			&models.PhoneVerification{},
+			&models.LoginAttempt{},
+		}
+		for _, model := range cleanup {
+			if err := tx.Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("email = ?", email).Delete(&models.LoginAttempt{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("follower_id = ? OR following_id = ?", user.ID, user.ID).Delete(&models.Follow{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("blocker_id = ? OR blocked_id = ?", user.ID, user.ID).Delete(&models.Block{}).Error; err != nil {
+			return err
+		}
+		// Ratings still count for the people they met, the text goes
+		if err := tx.Model(&models.Review{}).Where("reviewer_id = ?", user.ID).Update("comment", "").Error; err != nil {
+			return err
+		}
+
+		// Keep the row (other records point to it) but without anything personal
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"username":               fmt.Sprintf("deleted_%d", user.ID),
+			"email":                  fmt.Sprintf("deleted_%d@deleted.invalid", user.ID),
+			"password":               "",
+			"full_name":              "Deleted user",
+			"phone":                  nil,
+			"phone_verified":         false,
+			"phone_verified_at":      nil,
+			"require_verified_phone": false,
+			"image_url":              "",
+			"is_verified":            false,
+			"email_verified_at":      nil,
+			"totp_enabled":           false,
+			"totp_secret":            "",
+			"address":                "",
+			"city":                   "",
+			"province":               "",
+			"latitude":               0,
+			"longitude":              0,
+			"is_online":              false,
+			"anonymized_at":          now,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(user).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// Files are removed last: a failed transaction must not leave listings without images
+	for _, upload := range uploads {
+		if err := utils.RemoveUploadedFile(upload.URL); err != nil {
+			log.Printf("Failed to remove upload %s of user %d: %v", upload.URL, user.ID, err)
+			continue
+		}
+		p.DB.Delete(&upload)
+	}
+
+	return nil
+}
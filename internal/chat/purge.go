@@ -0,0 +1,44 @@
+package chat
+
+import (
+	"log"
+	"meetup_backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MessagePurger removes read messages once they can no longer be reported.
+// Reading a message only hides it (soft delete) so the recipient can still
+// report it for Retention.
+type MessagePurger struct {
+	DB        *gorm.DB
+	Retention time.Duration
+}
+
+func NewMessagePurger(db *gorm.DB, retention time.Duration) *MessagePurger {
+	return &MessagePurger{DB: db, Retention: retention}
+}
+
+// Run purges expired messages every interval. It blocks, so start it in a goroutine.
+func (p *MessagePurger) Run(every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		if n, err := p.PurgeExpired(); err != nil {
+			log.Printf("Message purge failed: %v", err)
+		} else if n > 0 {
+			log.Printf("Purged %d read message(s)", n)
+		}
+		<-ticker.C
+	}
+}
+
+// PurgeExpired hard-deletes messages hidden longer than Retention ago
+func (p *MessagePurger) PurgeExpired() (int64, error) {
+	result := p.DB.Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at <= ?", time.Now().Add(-p.Retention)).
+		Delete(&models.Message{})
+	return result.RowsAffected, result.Error
+}
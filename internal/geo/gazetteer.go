@@ -0,0 +1,305 @@
+package geo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"meetup_backend/utils"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Area levels, mapped from the GeoNames feature codes ADM1, ADM2 and ADM3
+const (
+	LevelProvince = "province"
+	LevelCity     = "city"
+	LevelDistrict = "district"
+)
+
+// Reverse lookups only match areas whose centre is within this distance
+const maxReverseDistanceKm = 60
+
+// cellSize is the width of a spatial index cell in degrees
+const cellSize = 0.5
+
+// Area is an administrative area from the gazetteer
+type Area struct {
+	ID          int64   `json:"id"`
+	Name        string  `json:"name"`
+	Level       string  `json:"level"`
+	Province    string  `json:"province,omitempty"` // Empty for provinces themselves
+	City        string  `json:"city,omitempty"`     // Only for districts
+	CountryCode string  `json:"country_code"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	Population  int64   `json:"population"`
+
+	searchKeys []string // Lowercased name, ASCII name and alternate names
+	admin1     string   // country.admin1
+	admin2     string   // country.admin1.admin2
+}
+
+// Place is the result of a reverse lookup
+type Place struct {
+	Province string `json:"province"`
+	City     string `json:"city"`
+	District string `json:"district,omitempty"`
+}
+
+type cell struct{ lat, lng int }
+
+// Gazetteer is an in-memory index of administrative areas. It is read-only after
+// loading and safe for concurrent use. An empty Gazetteer finds nothing.
+type Gazetteer struct {
+	areas []*Area
+	grid  map[string]map[cell][]*Area // level -> cell -> areas
+}
+
+// New returns an empty gazetteer
+func New() *Gazetteer {
+	return &Gazetteer{grid: map[string]map[cell][]*Area{}}
+}
+
+// Load reads a gazetteer file in the GeoNames dump format
+// (https://download.geonames.org/export/dump/, e.g. ID.txt)
+func Load(path string) (*Gazetteer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads tab separated GeoNames rows. Only administrative areas (feature
+// class A, codes ADM1-ADM3) are kept; other rows are skipped.
+func Parse(r io.Reader) (*Gazetteer, error) {
+	g := New()
+	provinces := map[string]string{}
+	cities := map[string]string{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024) // alternate names can be long
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, "\t")
+		if len(fields) < 15 {
+			return nil, fmt.Errorf("gazetteer line %d: expected at least 15 columns, got %d", line, len(fields))
+		}
+		if fields[6] != "A" {
+			continue
+		}
+
+		var level string
+		switch fields[7] {
+		case "ADM1":
+			level = LevelProvince
+		case "ADM2":
+			level = LevelCity
+		case "ADM3":
+			level = LevelDistrict
+		default:
+			continue
+		}
+
+		id, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("gazetteer line %d: invalid id: %w", line, err)
+		}
+		lat, latErr := strconv.ParseFloat(fields[4], 64)
+		lng, lngErr := strconv.ParseFloat(fields[5], 64)
+		if latErr != nil || lngErr != nil {
+			return nil, fmt.Errorf("gazetteer line %d: invalid coordinates", line)
+		}
+		population, _ := strconv.ParseInt(fields[14], 10, 64)
+
+		area := &Area{
+			ID:          id,
+			Name:        fields[1],
+			Level:       level,
+			CountryCode: fields[8],
+			Latitude:    lat,
+			Longitude:   lng,
+			Population:  population,
+			admin1:      fields[8] + "." + fields[10],
+			admin2:      fields[8] + "." + fields[10] + "." + fields[11],
+		}
+		area.searchKeys = searchKeys(fields[1], fields[2], fields[3])
+
+		switch level {
+		case LevelProvince:
+			provinces[area.admin1] = area.Name
+		case LevelCity:
+			cities[area.admin2] = area.Name
+		}
+		g.add(area)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Parents can appear after their children, so names are resolved once everything is read
+	for _, area := range g.areas {
+		if area.Level != LevelProvince {
+			area.Province = provinces[area.admin1]
+		}
+		if area.Level == LevelDistrict {
+			area.City = cities[area.admin2]
+		}
+	}
+
+	return g, nil
+}
+
+func (g *Gazetteer) add(area *Area) {
+	g.areas = append(g.areas, area)
+
+	cells := g.grid[area.Level]
+	if cells == nil {
+		cells = map[cell][]*Area{}
+		g.grid[area.Level] = cells
+	}
+	key := cellOf(area.Latitude, area.Longitude)
+	cells[key] = append(cells[key], area)
+}
+
+// Len returns the number of areas loaded
+func (g *Gazetteer) Len() int {
+	return len(g.areas)
+}
+
+// Reverse finds the province, city and district containing the point. Areas are
+// matched by their nearest centre, which is good enough for labelling listings.
+func (g *Gazetteer) Reverse(lat, lng float64) (Place, bool) {
+	var place Place
+
+	city := g.nearest(LevelCity, lat, lng)
+	if city != nil {
+		place.City = city.Name
+		place.Province = city.Province
+	} else if province := g.nearest(LevelProvince, lat, lng); province != nil {
+		place.Province = province.Name
+	}
+
+	// Only trust a district that belongs to the city we found
+	if district := g.nearest(LevelDistrict, lat, lng); district != nil && (city == nil || district.admin2 == city.admin2) {
+		place.District = district.Name
+		if city == nil {
+			place.City = district.City
+			place.Province = district.Province
+		}
+	}
+
+	return place, place.Province != "" || place.City != ""
+}
+
+// nearest returns the closest area of a level within maxReverseDistanceKm
+func (g *Gazetteer) nearest(level string, lat, lng float64) *Area {
+	cells := g.grid[level]
+	if len(cells) == 0 {
+		return nil
+	}
+
+	minLat, maxLat, minLng, maxLng := utils.BoundingBox(lat, lng, maxReverseDistanceKm)
+	from, to := cellOf(minLat, minLng), cellOf(maxLat, maxLng)
+
+	var best *Area
+	bestDistance := math.Inf(1)
+	for cLat := from.lat; cLat <= to.lat; cLat++ {
+		for cLng := from.lng; cLng <= to.lng; cLng++ {
+			for _, area := range cells[cell{cLat, cLng}] {
+				distance := utils.HaversineKm(lat, lng, area.Latitude, area.Longitude)
+				if distance <= maxReverseDistanceKm && distance < bestDistance {
+					best, bestDistance = area, distance
+				}
+			}
+		}
+	}
+	return best
+}
+
+// Search returns areas whose name (or an alternate name) contains the query,
+// prefix matches first and then by population. level may be empty for all levels.
+func (g *Gazetteer) Search(query, level string, limit int) []Area {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" || limit <= 0 {
+		return nil
+	}
+
+	type match struct {
+		area   *Area
+		prefix bool
+	}
+	var matches []match
+	for _, area := range g.areas {
+		if level != "" && area.Level != level {
+			continue
+		}
+		found, prefix := false, false
+		for _, key := range area.searchKeys {
+			if strings.HasPrefix(key, query) {
+				found, prefix = true, true
+				break
+			}
+			if strings.Contains(key, query) {
+				found = true
+			}
+		}
+		if found {
+			matches = append(matches, match{area, prefix})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.prefix != b.prefix {
+			return a.prefix
+		}
+		if a.area.Population != b.area.Population {
+			return a.area.Population > b.area.Population
+		}
+		return a.area.Name < b.area.Name
+	})
+
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	results := make([]Area, 0, len(matches))
+	for _, m := range matches {
+		results = append(results, *m.area)
+	}
+	return results
+}
+
+func cellOf(lat, lng float64) cell {
+	return cell{int(math.Floor(lat / cellSize)), int(math.Floor(lng / cellSize))}
+}
+
+// searchKeys lowercases the names an area can be found by
+func searchKeys(name, asciiName, alternateNames string) []string {
+	seen := map[string]bool{}
+	var keys []string
+	add := func(s string) {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s != "" && !seen[s] {
+			seen[s] = true
+			keys = append(keys, s)
+		}
+	}
+
+	add(name)
+	add(asciiName)
+	for _, alt := range strings.Split(alternateNames, ",") {
+		add(alt)
+	}
+	return keys
+}
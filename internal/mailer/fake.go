@@ -0,0 +1,66 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FakeMailer keeps sent emails in memory instead of delivering them.
+// If Dir is set, every email is also written there as a .txt file so
+// links can be opened by hand during development.
+type FakeMailer struct {
+	Dir string
+
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewFakeMailer(dir string) *FakeMailer {
+	return &FakeMailer{Dir: dir}
+}
+
+func (m *FakeMailer) Send(msg Message) error {
+	m.mu.Lock()
+	m.messages = append(m.messages, msg)
+	m.mu.Unlock()
+
+	log.Printf("📧 [fake mailer] to=%s subject=%q", msg.To, msg.Subject)
+
+	if m.Dir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	filename := filepath.Join(m.Dir, fmt.Sprintf("%d_%s.txt", time.Now().UnixNano(), msg.To))
+	content := fmt.Sprintf("To: %s\nSubject: %s\n\n%s\n", msg.To, msg.Subject, msg.Body)
+	return os.WriteFile(filename, []byte(content), 0o644)
+}
+
+// Messages returns a copy of every email sent so far
+func (m *FakeMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]Message, len(m.messages))
+	copy(out, m.messages)
+	return out
+}
+
+// LastTo returns the most recent email sent to the given address
+func (m *FakeMailer) LastTo(to string) (Message, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To == to {
+			return m.messages[i], true
+		}
+	}
+	return Message{}, false
+}
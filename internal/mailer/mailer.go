@@ -0,0 +1,14 @@
+package mailer
+
+// Message is a single outgoing email
+type Message struct {
+	To      string
+	Subject string
+	Body    string // Plain text
+}
+
+// Mailer delivers emails. Handlers depend on this interface so the SMTP
+// implementation can be swapped for FakeMailer in development and tests.
+type Mailer interface {
+	Send(msg Message) error
+}
@@ -0,0 +1,235 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrInvalidIDToken = errors.New("invalid id token")
+
+// IDTokenClaims are the identity claims we use from a verified ID token
+type IDTokenClaims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+}
+
+// publicKey is a provider signing key from its JWKS, with the algorithm it may be used with
+type publicKey struct {
+	alg string // Empty when the JWK does not pin an algorithm
+	key interface{}
+}
+
+// jwk is a key in the provider's JWKS document (RFC 7517)
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+
+	// EC and OKP
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// keysRefreshInterval limits how often an unknown kid triggers a JWKS download
+const keysRefreshInterval = 10 * time.Second
+
+// VerifyIDToken checks the signature against the provider's JWKS, the standard
+// claims (iss, aud, exp, iat) and the nonce sent in the authorization request
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*IDTokenClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims,
+		func(t *jwt.Token) (interface{}, error) { return p.keyFunc(ctx, t) },
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithIssuer(p.cfg.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if claimNonce, _ := claims["nonce"].(string); nonce == "" || claimNonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	// With several audiences the token must have been issued to us (OIDC Core 3.1.3.7)
+	if aud, _ := claims.GetAudience(); len(aud) > 1 {
+		if azp, _ := claims["azp"].(string); azp != p.cfg.ClientID {
+			return nil, fmt.Errorf("%w: azp mismatch", ErrInvalidIDToken)
+		}
+	}
+
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, fmt.Errorf("%w: missing sub", ErrInvalidIDToken)
+	}
+
+	result := &IDTokenClaims{Subject: subject}
+	result.Email, _ = claims["email"].(string)
+	result.Name, _ = claims["name"].(string)
+	result.Picture, _ = claims["picture"].(string)
+
+	// Some providers send email_verified as a string
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		result.EmailVerified = v
+	case string:
+		result.EmailVerified = v == "true"
+	}
+
+	return result, nil
+}
+
+// keyFunc finds the key for the token's kid and pins the algorithm to the key type
+func (p *Provider) keyFunc(ctx context.Context, t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	key, ok := p.lookupKey(ctx, kid, false)
+	if !ok {
+		// The provider may have rotated its keys since we last fetched them
+		key, ok = p.lookupKey(ctx, kid, true)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	alg := t.Method.Alg()
+	if key.alg != "" && key.alg != alg {
+		return nil, fmt.Errorf("key %q is not valid for %s", kid, alg)
+	}
+
+	switch key.key.(type) {
+	case *rsa.PublicKey:
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); ok {
+			return key.key, nil
+		}
+		if _, ok := t.Method.(*jwt.SigningMethodRSAPSS); ok {
+			return key.key, nil
+		}
+	case *ecdsa.PublicKey:
+		if _, ok := t.Method.(*jwt.SigningMethodECDSA); ok {
+			return key.key, nil
+		}
+	case ed25519.PublicKey:
+		if _, ok := t.Method.(*jwt.SigningMethodEd25519); ok {
+			return key.key, nil
+		}
+	}
+	return nil, fmt.Errorf("key %q is not valid for %s", kid, alg)
+}
+
+// lookupKey returns the key with the given kid, downloading the JWKS first when
+// nothing has been fetched yet or when refresh is set (rate limited)
+func (p *Provider) lookupKey(ctx context.Context, kid string, refresh bool) (publicKey, bool) {
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return publicKey{}, false
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	stale := time.Since(p.keysFetchedAt) > keysRefreshInterval
+	if p.keys == nil || (refresh && stale) {
+		var set struct {
+			Keys []jwk `json:"keys"`
+		}
+		p.keysFetchedAt = time.Now()
+		if err := p.getJSON(ctx, doc.JWKSURI, &set); err != nil {
+			return publicKey{}, false
+		}
+
+		keys := make(map[string]publicKey, len(set.Keys))
+		for _, k := range set.Keys {
+			if k.Use != "" && k.Use != "sig" {
+				continue
+			}
+			parsed, err := parseJWK(k)
+			if err != nil {
+				continue // Skip key types we do not support
+			}
+			keys[k.Kid] = publicKey{alg: k.Alg, key: parsed}
+		}
+		p.keys = keys
+	}
+
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+func parseJWK(k jwk) (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(bytes) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(bytes), nil
+}
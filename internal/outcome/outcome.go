@@ -0,0 +1,356 @@
+package outcome
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"meetup_backend/internal/points"
+	"meetup_backend/internal/ws"
+	"meetup_backend/models"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrMeetupNotFound    = errors.New("meetup not found")
+	ErrNotParticipant    = errors.New("not a participant of this meetup")
+	ErrInvalidOutcome    = errors.New("outcome must be completed or no_show")
+	ErrInvalidAbsentUser = errors.New("absent user must be a participant of this meetup")
+	ErrAlreadyReported   = errors.New("outcome already reported")
+	ErrWindowClosed      = errors.New("outcome window has closed")
+	ErrAlreadySettled    = errors.New("meetup is already settled")
+	ErrNotDisputed       = errors.New("meetup is not disputed")
+	ErrInvalidVerdict    = errors.New("verdict must be completed, no_show or cancelled")
+	ErrOwnDispute        = errors.New("moderators cannot resolve their own disputes")
+)
+
+// Service settles meetups from what their participants report afterwards.
+//
+// Each participant reports "completed" or "no_show" naming who did not come,
+// themselves included. Matching reports settle the meetup at once; when the
+// window ends, the reports received so far stand uncontested. Reports that
+// disagree open a dispute for moderators. Participants who did not show up get
+// a no-show on their profile and the others get the meetup fee back.
+type Service struct {
+	DB     *gorm.DB
+	Hub    *ws.Hub
+	Window time.Duration // How long after confirmation outcomes can be reported
+}
+
+func NewService(db *gorm.DB, hub *ws.Hub, window time.Duration) *Service {
+	return &Service{DB: db, Hub: hub, Window: window}
+}
+
+// Deadline is when reporting on the meetup closes
+func (s *Service) Deadline(meetup *models.Meetup) time.Time {
+	return meetup.ConfirmedAt.Add(s.Window)
+}
+
+// Report records what the user says happened. absentUserID is only used for
+// no_show and defaults to the other participant of a two-person meetup.
+func (s *Service) Report(meetupID, userID uint, outcome string, absentUserID *uint) (*models.Meetup, error) {
+	if outcome != models.OutcomeCompleted && outcome != models.OutcomeNoShow {
+		return nil, ErrInvalidOutcome
+	}
+
+	var meetup models.Meetup
+	changed := false
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lock(tx, meetupID, &meetup); err != nil {
+			return err
+		}
+
+		participant := findParticipant(&meetup, userID)
+		if participant == nil {
+			return ErrNotParticipant
+		}
+		if meetup.Status != models.MeetupConfirmed {
+			return ErrAlreadySettled
+		}
+		if participant.ReportedAt != nil {
+			return ErrAlreadyReported
+		}
+		if time.Now().After(s.Deadline(&meetup)) {
+			return ErrWindowClosed
+		}
+
+		var absent *uint
+		if outcome == models.OutcomeNoShow {
+			var err error
+			if absent, err = absentParticipant(&meetup, userID, absentUserID); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		participant.Outcome = outcome
+		participant.AbsentUserID = absent
+		participant.ReportedAt = &now
+		if err := tx.Model(participant).Updates(map[string]interface{}{
+			"outcome":        outcome,
+			"absent_user_id": absent,
+			"reported_at":    now,
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		changed, err = s.evaluate(tx, &meetup, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if changed {
+		s.notify(&meetup)
+	}
+	return &meetup, nil
+}
+
+// Resolve settles a disputed meetup by a moderator's verdict: completed,
+// no_show (with the participant who did not come) or cancelled, which refunds
+// everyone without a no-show.
+func (s *Service) Resolve(meetupID, moderatorID uint, verdict string, noShowUserID *uint, note string) (*models.Meetup, error) {
+	var meetup models.Meetup
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lock(tx, meetupID, &meetup); err != nil {
+			return err
+		}
+		if meetup.Status != models.MeetupDisputed {
+			return ErrNotDisputed
+		}
+		if findParticipant(&meetup, moderatorID) != nil {
+			return ErrOwnDispute
+		}
+
+		var absent []uint
+		switch verdict {
+		case models.MeetupCompleted, models.MeetupCancelled:
+		case models.MeetupNoShow:
+			if noShowUserID == nil || findParticipant(&meetup, *noShowUserID) == nil {
+				return ErrInvalidAbsentUser
+			}
+			absent = []uint{*noShowUserID}
+		default:
+			return ErrInvalidVerdict
+		}
+
+		meetup.ResolvedBy = &moderatorID
+		meetup.ResolutionNote = note
+		if err := tx.Model(&meetup).Updates(map[string]interface{}{
+			"resolved_by":     moderatorID,
+			"resolution_note": note,
+		}).Error; err != nil {
+			return err
+		}
+		return settle(tx, &meetup, verdict, absent)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify(&meetup)
+	return &meetup, nil
+}
+
+// evaluate settles or disputes the meetup once its reports allow it. After the
+// deadline (overdue) missing reports no longer hold the meetup open.
+func (s *Service) evaluate(tx *gorm.DB, meetup *models.Meetup, overdue bool) (bool, error) {
+	reported := 0
+	agreed := true
+	var absent uint // 0 while everyone says the meetup happened
+	for _, p := range meetup.Participants {
+		if p.ReportedAt == nil {
+			continue
+		}
+		claim := uint(0)
+		if p.Outcome == models.OutcomeNoShow && p.AbsentUserID != nil {
+			claim = *p.AbsentUserID
+		}
+		reported++
+		if reported == 1 {
+			absent = claim
+		} else if claim != absent {
+			agreed = false
+		}
+	}
+
+	switch {
+	case !agreed:
+		now := time.Now()
+		meetup.Status = models.MeetupDisputed
+		meetup.DisputedAt = &now
+		return true, tx.Model(meetup).Updates(map[string]interface{}{
+			"status":      meetup.Status,
+			"disputed_at": now,
+		}).Error
+	case reported < len(meetup.Participants) && !overdue:
+		return false, nil
+	case absent == 0:
+		return true, settle(tx, meetup, models.MeetupCompleted, nil)
+	default:
+		return true, settle(tx, meetup, models.MeetupNoShow, []uint{absent})
+	}
+}
+
+// settle closes the meetup. Absent participants get a no-show; unless the
+// meetup was completed, everyone else gets the meetup fee back.
+func settle(tx *gorm.DB, meetup *models.Meetup, status string, absent []uint) error {
+	for i := range meetup.Participants {
+		p := &meetup.Participants[i]
+
+		if slices.Contains(absent, p.UserID) {
+			p.NoShow = true
+			if err := tx.Model(p).Update("no_show", true).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.User{}).Where("id = ?", p.UserID).
+				Update("no_show_count", gorm.Expr("no_show_count + 1")).Error; err != nil {
+				return err
+			}
+			continue
+		}
+
+		if status == models.MeetupCompleted || meetup.PointsCost == 0 {
+			continue
+		}
+		_, err := points.Apply(tx, points.Change{
+			UserID:         p.UserID,
+			Delta:          meetup.PointsCost,
+			Reason:         points.ReasonMeetupRefund,
+			ChatRoomID:     &meetup.ChatRoomID,
+			MeetupID:       &meetup.ID,
+			IdempotencyKey: fmt.Sprintf("meetup:%d:refund:%d", meetup.ID, p.UserID),
+		})
+		// Deleted accounts have no balance to refund
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+
+	now := time.Now()
+	meetup.Status = status
+	meetup.SettledAt = &now
+	return tx.Model(meetup).Updates(map[string]interface{}{
+		"status":     status,
+		"settled_at": now,
+	}).Error
+}
+
+// Run settles meetups whose outcome window has ended every interval. It blocks,
+// so start it in a goroutine.
+func (s *Service) Run(every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		if n, err := s.SettleOverdue(); err != nil {
+			log.Printf("Settling meetups failed: %v", err)
+		} else if n > 0 {
+			log.Printf("Settled %d meetup(s) after their outcome window", n)
+		}
+		<-ticker.C
+	}
+}
+
+// SettleOverdue settles confirmed meetups past their deadline with the reports
+// they have
+func (s *Service) SettleOverdue() (int, error) {
+	var ids []uint
+	if err := s.DB.Model(&models.Meetup{}).
+		Where("status = ? AND confirmed_at <= ?", models.MeetupConfirmed, time.Now().Add(-s.Window)).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	settled := 0
+	for _, id := range ids {
+		var meetup models.Meetup
+		changed := false
+		err := s.DB.Transaction(func(tx *gorm.DB) error {
+			if err := lock(tx, id, &meetup); err != nil {
+				return err
+			}
+			if meetup.Status != models.MeetupConfirmed {
+				return nil // Settled in the meantime
+			}
+			var err error
+			changed, err = s.evaluate(tx, &meetup, true)
+			return err
+		})
+		if err != nil {
+			log.Printf("Failed to settle meetup %d: %v", id, err)
+			continue
+		}
+		if changed {
+			settled++
+			s.notify(&meetup)
+		}
+	}
+	return settled, nil
+}
+
+// notify tells the participants that the meetup was settled or disputed
+func (s *Service) notify(meetup *models.Meetup) {
+	noShows := []uint{}
+	for _, p := range meetup.Participants {
+		if p.NoShow {
+			noShows = append(noShows, p.UserID)
+		}
+	}
+	eventJSON, _ := json.Marshal(map[string]interface{}{
+		"type":             "meetup_outcome",
+		"meetup_id":        meetup.ID,
+		"chat_room_id":     meetup.ChatRoomID,
+		"status":           meetup.Status,
+		"no_show_user_ids": noShows,
+	})
+	for _, p := range meetup.Participants {
+		s.Hub.SendToUser(p.UserID, eventJSON)
+	}
+}
+
+// lock loads the meetup for update together with its participants
+func lock(tx *gorm.DB, meetupID uint, meetup *models.Meetup) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(meetup, meetupID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrMeetupNotFound
+		}
+		return err
+	}
+	return tx.Where("meetup_id = ?", meetup.ID).Order("id").Find(&meetup.Participants).Error
+}
+
+func findParticipant(meetup *models.Meetup, userID uint) *models.MeetupParticipant {
+	for i := range meetup.Participants {
+		if meetup.Participants[i].UserID == userID {
+			return &meetup.Participants[i]
+		}
+	}
+	return nil
+}
+
+// absentParticipant validates who the reporter says did not show up
+func absentParticipant(meetup *models.Meetup, reporterID uint, absentUserID *uint) (*uint, error) {
+	if absentUserID == nil {
+		if len(meetup.Participants) != 2 {
+			return nil, ErrInvalidAbsentUser
+		}
+		for _, p := range meetup.Participants {
+			if p.UserID != reporterID {
+				id := p.UserID
+				return &id, nil
+			}
+		}
+		return nil, ErrInvalidAbsentUser
+	}
+	if findParticipant(meetup, *absentUserID) == nil {
+		return nil, ErrInvalidAbsentUser
+	}
+	return absentUserID, nil
+}
@@ -0,0 +1,119 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// FakeSignatureHeader carries the hex HMAC-SHA256 of the webhook body
+const FakeSignatureHeader = "X-Fake-Signature"
+
+// FakeProvider accepts every checkout and lets developers trigger webhooks
+// with Simulate instead of paying. Webhooks are signed like a real gateway's.
+type FakeProvider struct {
+	secret  []byte
+	baseURL string
+}
+
+// NewFakeProvider signs webhooks with secret. baseURL is the public URL of this
+// server, used to build payment links.
+func NewFakeProvider(secret, baseURL string) *FakeProvider {
+	return &FakeProvider{secret: []byte(secret), baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) CreateCheckout(req CheckoutRequest) (*Checkout, error) {
+	ref, err := randomID("fake_")
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("💳 [fake payment] order=%s amount=%d %s ref=%s", req.OrderReference, req.Amount, req.Currency, ref)
+	return &Checkout{
+		ProviderReference: ref,
+		PaymentURL:        p.baseURL + "/api/payments/fake/orders/" + req.OrderReference,
+	}, nil
+}
+
+// fakeWebhook is the body of a fake webhook
+type fakeWebhook struct {
+	ID                string `json:"id"`
+	OrderReference    string `json:"order_reference"`
+	ProviderReference string `json:"provider_reference"`
+	Status            string `json:"status"`
+	Amount            int64  `json:"amount"`
+	Currency          string `json:"currency"`
+}
+
+func (p *FakeProvider) ParseWebhook(header http.Header, body []byte) (*Event, error) {
+	signature, err := hex.DecodeString(header.Get(FakeSignatureHeader))
+	if err != nil || !hmac.Equal(signature, p.sign(body)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var hook fakeWebhook
+	if err := json.Unmarshal(body, &hook); err != nil {
+		return nil, fmt.Errorf("invalid webhook body: %w", err)
+	}
+	if hook.ID == "" || hook.OrderReference == "" || hook.Status == "" {
+		return nil, errors.New("invalid webhook body: id, order_reference and status are required")
+	}
+
+	return &Event{
+		ID:                hook.ID,
+		OrderReference:    hook.OrderReference,
+		ProviderReference: hook.ProviderReference,
+		Status:            hook.Status,
+		Amount:            hook.Amount,
+		Currency:          hook.Currency,
+	}, nil
+}
+
+// Simulate builds the signed webhook the fake gateway would send when the
+// payment reaches status
+func (p *FakeProvider) Simulate(orderReference, providerReference, status string, amount int64, currency string) (http.Header, []byte, error) {
+	id, err := randomID("evt_")
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := json.Marshal(fakeWebhook{
+		ID:                id,
+		OrderReference:    orderReference,
+		ProviderReference: providerReference,
+		Status:            status,
+		Amount:            amount,
+		Currency:          currency,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := http.Header{}
+	header.Set(FakeSignatureHeader, hex.EncodeToString(p.sign(body)))
+	return header, body, nil
+}
+
+func (p *FakeProvider) sign(body []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+func randomID(prefix string) (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,54 @@
+package payment
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Payment statuses reported by providers
+const (
+	StatusPending  = "pending"
+	StatusPaid     = "paid"
+	StatusFailed   = "failed"
+	StatusExpired  = "expired"
+	StatusRefunded = "refunded"
+)
+
+var ErrInvalidSignature = errors.New("webhook signature is invalid")
+
+// CheckoutRequest asks the provider to collect a payment for one order
+type CheckoutRequest struct {
+	OrderReference string // Our order reference, echoed back in webhooks
+	Amount         int64  // In the smallest unit of the currency (IDR has no subunit)
+	Currency       string
+	Description    string
+	CustomerEmail  string
+	ExpiresAt      time.Time
+}
+
+// Checkout is where the user completes the payment
+type Checkout struct {
+	ProviderReference string
+	PaymentURL        string
+}
+
+// Event is a verified status change sent by the provider
+type Event struct {
+	ID                string // Unique per provider, used to ignore redelivered webhooks
+	OrderReference    string
+	ProviderReference string
+	Status            string
+	Amount            int64
+	Currency          string
+}
+
+// Provider collects payments. Handlers depend on this interface so a real
+// gateway can be swapped for FakeProvider in development and tests.
+// Payments are only confirmed through webhooks, never by the client.
+type Provider interface {
+	Name() string
+	CreateCheckout(req CheckoutRequest) (*Checkout, error)
+	// ParseWebhook verifies the request signature and returns the event it carries
+	ParseWebhook(header http.Header, body []byte) (*Event, error)
+}
@@ -0,0 +1,186 @@
+package points
+
+import (
+	"errors"
+	"fmt"
+	"meetup_backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Amounts used across the app
+const (
+	SignupBonus = 10 // Credited to every new account
+	MeetupFee   = 5  // Charged to each participant when a meetup is confirmed
+)
+
+// Ledger reasons
+const (
+	ReasonOpeningBalance = "opening_balance" // Balance from before the ledger existed
+	ReasonSignupBonus    = "signup_bonus"
+	ReasonMeetupFee      = "meetup_fee"
+	ReasonMeetupRefund   = "meetup_refund" // The meetup fee back after the other side did not show up
+	ReasonAdminReset     = "admin_reset"
+	ReasonTopUp          = "topup"
+	ReasonTopUpRefund    = "topup_refund" // A paid top-up was refunded by the payment provider
+)
+
+var (
+	ErrInsufficientPoints = errors.New("insufficient points")
+	ErrZeroDelta          = errors.New("points change must not be zero")
+)
+
+// Change describes one balance change. Delta is positive for credits and
+// negative for debits.
+type Change struct {
+	UserID     uint
+	Delta      int
+	Reason     string
+	Note       string
+	ChatRoomID *uint
+	MeetupID   *uint
+	ActorID    *uint
+
+	// Changes with a key are applied at most once; applying the same key again
+	// returns the original entry. Keys are global, so include the user ID.
+	IdempotencyKey string
+}
+
+// Service is the single place where point balances change. Every change writes
+// the ledger entry and the balance in one transaction and never lets a balance
+// go below zero.
+type Service struct {
+	db *gorm.DB
+}
+
+// NewService returns a points service using db
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// Apply records the change in its own transaction
+func (s *Service) Apply(change Change) (*models.PointTransaction, error) {
+	var entry *models.PointTransaction
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		entry, err = Apply(tx, change)
+		return err
+	})
+	return entry, err
+}
+
+// SetBalance records whatever change brings the balance to exactly balance. It
+// returns ErrZeroDelta without writing anything when the balance already is balance.
+func (s *Service) SetBalance(userID uint, balance int, change Change) (*models.PointTransaction, error) {
+	var entry *models.PointTransaction
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		change.UserID = userID
+		entry, err = apply(tx, change, func(current int) int { return balance - current })
+		return err
+	})
+	return entry, err
+}
+
+// Apply records the change inside tx, for callers that update other rows in the
+// same transaction. A failed change must roll back tx.
+func Apply(tx *gorm.DB, change Change) (*models.PointTransaction, error) {
+	if change.Delta == 0 {
+		return nil, ErrZeroDelta
+	}
+	return apply(tx, change, func(int) int { return change.Delta })
+}
+
+// apply locks the user row, so changes to one balance are serialised and the
+// idempotency check below cannot race
+func apply(tx *gorm.DB, change Change, delta func(current int) int) (*models.PointTransaction, error) {
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id, points").First(&user, change.UserID).Error; err != nil {
+		return nil, err
+	}
+
+	var key *string
+	if change.IdempotencyKey != "" {
+		key = &change.IdempotencyKey
+
+		var existing models.PointTransaction
+		err := tx.Where("idempotency_key = ?", change.IdempotencyKey).First(&existing).Error
+		if err == nil {
+			if existing.UserID != change.UserID {
+				return nil, fmt.Errorf("idempotency key %q belongs to another user", change.IdempotencyKey)
+			}
+			return &existing, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	d := delta(user.Points)
+	if d == 0 {
+		return nil, ErrZeroDelta // The ledger only holds changes
+	}
+	balance := user.Points + d
+	if balance < 0 {
+		return nil, ErrInsufficientPoints
+	}
+
+	if err := tx.Model(&user).Update("points", balance).Error; err != nil {
+		return nil, err
+	}
+
+	entry := models.PointTransaction{
+		UserID:         user.ID,
+		Delta:          d,
+		BalanceAfter:   balance,
+		Reason:         change.Reason,
+		Note:           change.Note,
+		ChatRoomID:     change.ChatRoomID,
+		MeetupID:       change.MeetupID,
+		ActorID:        change.ActorID,
+		IdempotencyKey: key,
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GrantSignupBonus credits the registration bonus to a new account
+func GrantSignupBonus(tx *gorm.DB, userID uint) error {
+	_, err := Apply(tx, Change{
+		UserID:         userID,
+		Delta:          SignupBonus,
+		Reason:         ReasonSignupBonus,
+		IdempotencyKey: fmt.Sprintf("signup:%d", userID),
+	})
+	return err
+}
+
+// BackfillOpeningBalances gives users whose balance predates the ledger an entry
+// for it, so every balance equals the sum of its ledger
+func BackfillOpeningBalances(db *gorm.DB) error {
+	var users []models.User
+	err := db.Unscoped().Select("id, points").
+		Where("points <> 0 AND id NOT IN (?)", db.Model(&models.PointTransaction{}).Select("user_id")).
+		Find(&users).Error
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		key := fmt.Sprintf("opening:%d", user.ID)
+		entry := models.PointTransaction{
+			UserID:         user.ID,
+			Delta:          user.Points,
+			BalanceAfter:   user.Points,
+			Reason:         ReasonOpeningBalance,
+			IdempotencyKey: &key,
+		}
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&entry).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package points
+
+import (
+	"errors"
+	"testing"
+
+	"meetup_backend/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestService(t *testing.T) (*Service, *gorm.DB) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&models.User{}, &models.PointTransaction{}); err != nil {
+		t.Fatal(err)
+	}
+	return NewService(db), db
+}
+
+func TestSetBalanceToCurrentBalanceWritesNothing(t *testing.T) {
+	s, db := newTestService(t)
+
+	user := models.User{Username: "alice", Email: "alice@example.com", Points: 10}
+	db.Create(&user)
+
+	if _, err := s.SetBalance(user.ID, 10, Change{Reason: ReasonAdminReset}); !errors.Is(err, ErrZeroDelta) {
+		t.Fatalf("SetBalance error = %v, want %v", err, ErrZeroDelta)
+	}
+	var entries int64
+	db.Model(&models.PointTransaction{}).Count(&entries)
+	if entries != 0 {
+		t.Fatalf("%d ledger entries written, want 0", entries)
+	}
+
+	entry, err := s.SetBalance(user.ID, 3, Change{Reason: ReasonAdminReset})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Delta != -7 || entry.BalanceAfter != 3 {
+		t.Fatalf("entry delta %d balance %d, want -7 and 3", entry.Delta, entry.BalanceAfter)
+	}
+}
@@ -0,0 +1,59 @@
+package sms
+
+import (
+	"log"
+	"sync"
+)
+
+// FakeGateway does not deliver text messages. It only logs the recipient, never
+// the body, since bodies carry verification codes. A recording gateway also
+// keeps the messages in memory so tests can read the codes back.
+type FakeGateway struct {
+	mu       sync.Mutex
+	record   bool
+	messages []Message
+}
+
+func NewFakeGateway() *FakeGateway {
+	return &FakeGateway{}
+}
+
+// NewRecordingGateway returns a FakeGateway that keeps every message. For tests only:
+// nothing is ever removed.
+func NewRecordingGateway() *FakeGateway {
+	return &FakeGateway{record: true}
+}
+
+func (g *FakeGateway) Send(msg Message) error {
+	if g.record {
+		g.mu.Lock()
+		g.messages = append(g.messages, msg)
+		g.mu.Unlock()
+	}
+
+	log.Printf("📱 [fake sms] to=%s (not delivered)", msg.To)
+	return nil
+}
+
+// Messages returns a copy of every message recorded so far
+func (g *FakeGateway) Messages() []Message {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	out := make([]Message, len(g.messages))
+	copy(out, g.messages)
+	return out
+}
+
+// LastTo returns the most recent message recorded for the given number
+func (g *FakeGateway) LastTo(to string) (Message, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for i := len(g.messages) - 1; i >= 0; i-- {
+		if g.messages[i].To == to {
+			return g.messages[i], true
+		}
+	}
+	return Message{}, false
+}
@@ -0,0 +1,13 @@
+package sms
+
+// Message is a single outgoing text message
+type Message struct {
+	To   string // E.164, e.g. +6281234567890
+	Body string
+}
+
+// Gateway delivers text messages. Handlers depend on this interface so a
+// provider implementation can be swapped for FakeGateway in development and tests.
+type Gateway interface {
+	Send(msg Message) error
+}
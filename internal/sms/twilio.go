@@ -0,0 +1,62 @@
+package sms
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TwilioGateway sends text messages through the Twilio Messages API
+type TwilioGateway struct {
+	AccountSID string
+	AuthToken  string
+	From       string // Sender number or messaging service SID
+
+	client  *http.Client
+	baseURL string
+}
+
+func NewTwilioGateway(accountSID, authToken, from string) *TwilioGateway {
+	return &TwilioGateway{
+		AccountSID: accountSID,
+		AuthToken:  authToken,
+		From:       from,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		baseURL:    "https://api.twilio.com",
+	}
+}
+
+func (g *TwilioGateway) Send(msg Message) error {
+	form := url.Values{}
+	form.Set("To", msg.To)
+	form.Set("Body", msg.Body)
+	if strings.HasPrefix(g.From, "MG") {
+		form.Set("MessagingServiceSid", g.From)
+	} else {
+		form.Set("From", g.From)
+	}
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", g.baseURL, url.PathEscape(g.AccountSID))
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(g.AccountSID, g.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		// The error body names the problem, never the message text
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("twilio: status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
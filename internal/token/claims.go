@@ -0,0 +1,59 @@
+package token
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// IssueAccessToken signs a short-lived access token bound to a session
+func (s *Service) IssueAccessToken(userID uint, role string, sessionID uint, ttl time.Duration) (string, error) {
+	now := time.Now()
+	return s.Sign(jwt.MapClaims{
+		"user_id": userID,
+		"role":    role,
+		"sid":     sessionID,
+		"iat":     now.Unix(),
+		"exp":     now.Add(ttl).Unix(),
+	})
+}
+
+// IssueActionToken signs a single-purpose token such as an email verification link.
+// The purpose claim keeps it from being accepted as an access token and vice versa.
+func (s *Service) IssueActionToken(purpose string, userID uint, extra jwt.MapClaims, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"purpose": purpose,
+		"user_id": userID,
+		"iat":     now.Unix(),
+		"exp":     now.Add(ttl).Unix(),
+	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	return s.Sign(claims)
+}
+
+// ParseAccessToken validates an access token (session checks are done by the caller)
+func (s *Service) ParseAccessToken(tokenString string) (jwt.MapClaims, error) {
+	claims, err := s.Parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if _, isAction := claims["purpose"]; isAction {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// ParseActionToken validates a token created by IssueActionToken for the given purpose
+func (s *Service) ParseActionToken(tokenString string, purpose string) (jwt.MapClaims, error) {
+	claims, err := s.Parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if p, _ := claims["purpose"].(string); p != purpose {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
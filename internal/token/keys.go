@@ -0,0 +1,141 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// signingKey is a parsed models.SigningKey
+type signingKey struct {
+	kid     string
+	alg     string
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// OKP (Ed25519)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+func signingMethod(alg string) (jwt.SigningMethod, error) {
+	switch alg {
+	case AlgRS256:
+		return jwt.SigningMethodRS256, nil
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("unsupported signing algorithm: %s", alg)
+}
+
+// generateKeyPair creates a new key pair and returns it PEM encoded
+func generateKeyPair(alg string) (privatePEM string, publicPEM string, err error) {
+	var private crypto.Signer
+	switch alg {
+	case AlgRS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		err = fmt.Errorf("unsupported signing algorithm: %s", alg)
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", "", err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		return "", "", err
+	}
+
+	privatePEM = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}))
+	publicPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+	return privatePEM, publicPEM, nil
+}
+
+// parseKeyPair decodes the PEM encoded keys stored in the database
+func parseKeyPair(kid, alg, privatePEM, publicPEM string) (*signingKey, error) {
+	privateBlock, _ := pem.Decode([]byte(privatePEM))
+	publicBlock, _ := pem.Decode([]byte(publicPEM))
+	if privateBlock == nil || publicBlock == nil {
+		return nil, errors.New("invalid PEM data")
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(privateBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(publicBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key cannot sign")
+	}
+
+	return &signingKey{kid: kid, alg: alg, private: signer, public: publicKey}, nil
+}
+
+// jwk converts the public half of the key to JWK format
+func (k *signingKey) jwk() (JWK, error) {
+	enc := base64.RawURLEncoding
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Kid: k.kid,
+			Use: "sig",
+			Alg: k.alg,
+			N:   enc.EncodeToString(pub.N.Bytes()),
+			E:   enc.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, nil
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Kid: k.kid,
+			Use: "sig",
+			Alg: k.alg,
+			Crv: "Ed25519",
+			X:   enc.EncodeToString(pub),
+		}, nil
+	}
+	return JWK{}, fmt.Errorf("unsupported public key type %T", k.public)
+}
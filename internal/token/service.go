@@ -0,0 +1,301 @@
+package token
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"meetup_backend/models"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidToken = errors.New("token is invalid")
+	ErrTokenExpired = errors.New("token has expired")
+)
+
+// Options configures the token service
+type Options struct {
+	Algorithm        string        // RS256 or EdDSA, used for newly generated keys
+	Issuer           string        // iss claim, checked on every token
+	Audience         string        // aud claim, checked on every token
+	RotationInterval time.Duration // How long a key signs new tokens before a new key replaces it
+	VerifyGrace      time.Duration // How long a replaced key keeps verifying; must cover the longest token lifetime
+}
+
+// Service is the single place where JWTs are signed and validated.
+// Keys are identified by kid; the newest key signs, every unexpired key verifies.
+type Service struct {
+	db   *gorm.DB
+	opts Options
+
+	mu               sync.RWMutex
+	current          *signingKey
+	currentRetiresAt time.Time
+	keys             map[string]*signingKey
+	jwks             JWKSet
+	lastReload       time.Time
+
+	rotateMu sync.Mutex
+}
+
+// NewService loads the keys from the database, creating the first key if there is none
+func NewService(db *gorm.DB, opts Options) (*Service, error) {
+	if _, err := signingMethod(opts.Algorithm); err != nil {
+		return nil, err
+	}
+
+	s := &Service{
+		db:   db,
+		opts: opts,
+		keys: make(map[string]*signingKey),
+	}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	if _, err := s.signingKey(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Run periodically picks up keys created by other instances, rotates the
+// signing key before it retires and deletes expired keys.
+func (s *Service) Run(checkEvery time.Duration) {
+	ticker := time.NewTicker(checkEvery)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := s.reload(); err != nil {
+			log.Printf("Failed to reload signing keys: %v", err)
+			continue
+		}
+
+		s.mu.RLock()
+		due := s.current == nil || time.Until(s.currentRetiresAt) < checkEvery
+		s.mu.RUnlock()
+
+		if due {
+			if err := s.Rotate(); err != nil {
+				log.Printf("Failed to rotate signing key: %v", err)
+			}
+		}
+
+		if err := s.db.Where("expires_at < ?", time.Now()).Delete(&models.SigningKey{}).Error; err != nil {
+			log.Printf("Failed to delete expired signing keys: %v", err)
+		}
+	}
+}
+
+// Rotate creates a new signing key. The previous keys stop signing immediately
+// but keep verifying for VerifyGrace so already issued tokens stay valid.
+func (s *Service) Rotate() error {
+	s.rotateMu.Lock()
+	defer s.rotateMu.Unlock()
+
+	kidBytes := make([]byte, 8)
+	if _, err := rand.Read(kidBytes); err != nil {
+		return err
+	}
+	kid := hex.EncodeToString(kidBytes)
+
+	privatePEM, publicPEM, err := generateKeyPair(s.opts.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	key := models.SigningKey{
+		KID:        kid,
+		Algorithm:  s.opts.Algorithm,
+		PrivateKey: privatePEM,
+		PublicKey:  publicPEM,
+		RetiresAt:  now.Add(s.opts.RotationInterval),
+		ExpiresAt:  now.Add(s.opts.RotationInterval + s.opts.VerifyGrace),
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.SigningKey{}).
+			Where("retires_at > ?", now).
+			Updates(map[string]interface{}{
+				"retires_at": now,
+				"expires_at": now.Add(s.opts.VerifyGrace),
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&key).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("🔑 New %s signing key created (kid: %s)", key.Algorithm, key.KID)
+	return s.reload()
+}
+
+// JWKS returns the public keys that are currently accepted
+func (s *Service) JWKS() JWKSet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.jwks
+}
+
+// Sign adds the standard claims (iss, aud, iat) and signs with the current key
+func (s *Service) Sign(claims jwt.MapClaims) (string, error) {
+	key, err := s.signingKey()
+	if err != nil {
+		return "", err
+	}
+
+	method, err := signingMethod(key.alg)
+	if err != nil {
+		return "", err
+	}
+
+	signed := jwt.MapClaims{}
+	for k, v := range claims {
+		signed[k] = v
+	}
+	signed["iss"] = s.opts.Issuer
+	signed["aud"] = s.opts.Audience
+	if _, ok := signed["iat"]; !ok {
+		signed["iat"] = time.Now().Unix()
+	}
+
+	token := jwt.NewWithClaims(method, signed)
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.private)
+}
+
+// Parse validates signature, algorithm, exp, iss and aud and returns the claims
+func (s *Service) Parse(tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.keyFunc,
+		jwt.WithValidMethods([]string{AlgRS256, AlgEdDSA}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithIssuer(s.opts.Issuer),
+		jwt.WithAudience(s.opts.Audience),
+	)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, ErrTokenExpired
+	}
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// keyFunc looks up the verification key by kid and pins the algorithm to the key's own
+func (s *Service) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("missing kid header")
+	}
+
+	key := s.lookup(kid)
+	if key == nil {
+		// The key may have been created by another instance since our last reload
+		s.reloadIfStale()
+		key = s.lookup(kid)
+	}
+	if key == nil {
+		return nil, fmt.Errorf("unknown kid: %s", kid)
+	}
+
+	if token.Method.Alg() != key.alg {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.public, nil
+}
+
+func (s *Service) lookup(kid string) *signingKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keys[kid]
+}
+
+// signingKey returns the key to sign with, rotating first if it has retired
+func (s *Service) signingKey() (*signingKey, error) {
+	s.mu.RLock()
+	key, retiresAt := s.current, s.currentRetiresAt
+	s.mu.RUnlock()
+
+	if key != nil && time.Now().Before(retiresAt) {
+		return key, nil
+	}
+
+	if err := s.Rotate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.current == nil {
+		return nil, errors.New("no signing key available")
+	}
+	return s.current, nil
+}
+
+// reloadIfStale reloads keys at most every few seconds, so a flood of tokens
+// with unknown kids cannot hammer the database
+func (s *Service) reloadIfStale() {
+	s.mu.RLock()
+	stale := time.Since(s.lastReload) > 5*time.Second
+	s.mu.RUnlock()
+
+	if stale {
+		if err := s.reload(); err != nil {
+			log.Printf("Failed to reload signing keys: %v", err)
+		}
+	}
+}
+
+// reload replaces the in-memory key set with the unexpired keys from the database
+func (s *Service) reload() error {
+	now := time.Now()
+
+	var rows []models.SigningKey
+	if err := s.db.Where("expires_at > ?", now).Order("created_at desc").Find(&rows).Error; err != nil {
+		return err
+	}
+
+	keys := make(map[string]*signingKey, len(rows))
+	jwks := JWKSet{Keys: []JWK{}}
+	var current *signingKey
+	var currentRetiresAt time.Time
+
+	for _, row := range rows {
+		key, err := parseKeyPair(row.KID, row.Algorithm, row.PrivateKey, row.PublicKey)
+		if err != nil {
+			log.Printf("Skipping unreadable signing key %s: %v", row.KID, err)
+			continue
+		}
+		keys[key.kid] = key
+
+		if jwk, err := key.jwk(); err == nil {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+
+		// Newest key of the configured algorithm that has not retired yet
+		if current == nil && row.Algorithm == s.opts.Algorithm && row.RetiresAt.After(now) {
+			current = key
+			currentRetiresAt = row.RetiresAt
+		}
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.jwks = jwks
+	s.current = current
+	s.currentRetiresAt = currentRetiresAt
+	s.lastReload = now
+	s.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,265 @@
+package topup
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"meetup_backend/internal/payment"
+	"meetup_backend/internal/points"
+	"meetup_backend/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrPackageNotFound   = errors.New("point package not found")
+	ErrTooManyPending    = errors.New("too many pending orders")
+	ErrUnknownOrder      = errors.New("order not found")
+	ErrUnsupportedStatus = errors.New("unsupported payment status")
+)
+
+// MaxPendingOrders limits unpaid orders per user
+const MaxPendingOrders = 5
+
+// Service sells point packages. Orders are created pending and only change
+// through provider webhooks (or expiry); points move through the ledger.
+type Service struct {
+	DB       *gorm.DB
+	Provider payment.Provider
+	Expiry   time.Duration // How long a checkout can be paid
+}
+
+func NewService(db *gorm.DB, provider payment.Provider, expiry time.Duration) *Service {
+	return &Service{DB: db, Provider: provider, Expiry: expiry}
+}
+
+// Checkout creates a pending order for the package and a payment at the provider
+func (s *Service) Checkout(user *models.User, packageID uint) (*models.PaymentOrder, error) {
+	var pkg models.PointPackage
+	if err := s.DB.Where("id = ? AND active = ?", packageID, true).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPackageNotFound
+		}
+		return nil, err
+	}
+
+	var pending int64
+	s.DB.Model(&models.PaymentOrder{}).
+		Where("user_id = ? AND status = ? AND expires_at > ?", user.ID, models.PaymentOrderPending, time.Now()).
+		Count(&pending)
+	if pending >= MaxPendingOrders {
+		return nil, ErrTooManyPending
+	}
+
+	reference, err := newReference()
+	if err != nil {
+		return nil, err
+	}
+	order := models.PaymentOrder{
+		Reference: reference,
+		UserID:    user.ID,
+		PackageID: pkg.ID,
+		Points:    pkg.Points,
+		Amount:    pkg.Price,
+		Currency:  pkg.Currency,
+		Provider:  s.Provider.Name(),
+		Status:    models.PaymentOrderPending,
+		ExpiresAt: time.Now().Add(s.Expiry),
+	}
+	if err := s.DB.Create(&order).Error; err != nil {
+		return nil, err
+	}
+
+	checkout, err := s.Provider.CreateCheckout(payment.CheckoutRequest{
+		OrderReference: order.Reference,
+		Amount:         order.Amount,
+		Currency:       order.Currency,
+		Description:    fmt.Sprintf("%s (%d points)", pkg.Name, pkg.Points),
+		CustomerEmail:  user.Email,
+		ExpiresAt:      order.ExpiresAt,
+	})
+	if err != nil {
+		s.DB.Model(&order).Update("status", models.PaymentOrderFailed)
+		return nil, fmt.Errorf("create checkout: %w", err)
+	}
+
+	order.ProviderReference = checkout.ProviderReference
+	order.PaymentURL = checkout.PaymentURL
+	if err := s.DB.Model(&order).Updates(map[string]interface{}{
+		"provider_reference": order.ProviderReference,
+		"payment_url":        order.PaymentURL,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// HandleEvent applies a verified webhook event. Redelivered events are ignored
+// and statuses that arrive out of order never undo a later state.
+func (s *Service) HandleEvent(event *payment.Event, payload []byte) (*models.PaymentOrder, error) {
+	var order models.PaymentOrder
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("reference = ? AND provider = ?", event.OrderReference, s.Provider.Name()).
+			First(&order).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUnknownOrder
+			}
+			return err
+		}
+
+		record := models.PaymentEvent{
+			Provider: s.Provider.Name(),
+			EventID:  event.ID,
+			OrderID:  &order.ID,
+			Status:   event.Status,
+			Payload:  string(payload),
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil // Already processed
+		}
+
+		return s.transition(tx, &order, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// transition moves the order to the event's status if that is a valid step
+func (s *Service) transition(tx *gorm.DB, order *models.PaymentOrder, event *payment.Event) error {
+	now := time.Now()
+
+	switch event.Status {
+	case payment.StatusPaid:
+		// A payment can still succeed after we gave up on it; the money arrived, so credit it
+		if order.Status == models.PaymentOrderPaid || order.Status == models.PaymentOrderRefunded {
+			return nil
+		}
+		if event.Amount != order.Amount || !strings.EqualFold(event.Currency, order.Currency) {
+			log.Printf("Payment for order %s ignored: paid %d %s, expected %d %s",
+				order.Reference, event.Amount, event.Currency, order.Amount, order.Currency)
+			return nil
+		}
+		if _, err := points.Apply(tx, points.Change{
+			UserID:         order.UserID,
+			Delta:          order.Points,
+			Reason:         points.ReasonTopUp,
+			Note:           order.Reference,
+			IdempotencyKey: "topup:" + order.Reference,
+		}); err != nil {
+			return err
+		}
+		order.Status = models.PaymentOrderPaid
+		order.PaidAt = &now
+
+	case payment.StatusFailed, payment.StatusExpired:
+		if order.Status != models.PaymentOrderPending {
+			return nil
+		}
+		order.Status = event.Status
+
+	case payment.StatusRefunded:
+		if order.Status != models.PaymentOrderPaid {
+			return nil
+		}
+		shortfall, err := takeBack(tx, order)
+		if err != nil {
+			return err
+		}
+		order.Status = models.PaymentOrderRefunded
+		order.RefundedAt = &now
+		order.RefundShortfall = shortfall
+
+	case payment.StatusPending:
+		return nil
+
+	default:
+		return ErrUnsupportedStatus
+	}
+
+	return tx.Model(order).Updates(map[string]interface{}{
+		"status":           order.Status,
+		"paid_at":          order.PaidAt,
+		"refunded_at":      order.RefundedAt,
+		"refund_shortfall": order.RefundShortfall,
+	}).Error
+}
+
+// takeBack debits the refunded points. Points already spent cannot be taken
+// back without a negative balance, so only the rest is debited and the
+// shortfall is returned for support to follow up. A deleted account has no
+// balance left, so all of it is a shortfall.
+func takeBack(tx *gorm.DB, order *models.PaymentOrder) (int, error) {
+	change := points.Change{
+		UserID:         order.UserID,
+		Delta:          -order.Points,
+		Reason:         points.ReasonTopUpRefund,
+		Note:           order.Reference,
+		IdempotencyKey: "topup-refund:" + order.Reference,
+	}
+	_, err := points.Apply(tx, change)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("Refund of order %s: user %d was deleted, %d points not taken back", order.Reference, order.UserID, order.Points)
+		return order.Points, nil
+	}
+	if !errors.Is(err, points.ErrInsufficientPoints) {
+		return 0, err
+	}
+
+	var user models.User
+	if err := tx.Select("id, points").First(&user, order.UserID).Error; err != nil {
+		return 0, err
+	}
+	shortfall := order.Points - user.Points
+	if user.Points > 0 {
+		change.Delta = -user.Points
+		if _, err := points.Apply(tx, change); err != nil {
+			return 0, err
+		}
+	}
+	log.Printf("Refund of order %s: %d points were already spent", order.Reference, shortfall)
+	return shortfall, nil
+}
+
+// Run expires unpaid orders every interval. It blocks, so start it in a goroutine.
+func (s *Service) Run(every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		if n, err := s.ExpireStale(); err != nil {
+			log.Printf("Expiring payment orders failed: %v", err)
+		} else if n > 0 {
+			log.Printf("Expired %d unpaid payment order(s)", n)
+		}
+		<-ticker.C
+	}
+}
+
+// ExpireStale marks pending orders past their expiry as expired. A late
+// "paid" webhook still credits them.
+func (s *Service) ExpireStale() (int64, error) {
+	result := s.DB.Model(&models.PaymentOrder{}).
+		Where("status = ? AND expires_at <= ?", models.PaymentOrderPending, time.Now()).
+		Update("status", models.PaymentOrderExpired)
+	return result.RowsAffected, result.Error
+}
+
+func newReference() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "PO-" + strings.ToUpper(hex.EncodeToString(b)), nil
+}
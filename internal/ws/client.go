@@ -32,12 +32,19 @@ type Client struct {
 	// The websocket connection.
 	Conn *websocket.Conn
 
-	// Buffered channel of outbound messages.
+	// Buffered channel of outbound messages. It is never closed; the hub
+	// closes done instead, so late sends from ReadPump cannot panic.
 	Send chan []byte
 
+	// Closed by the hub when the client is unregistered
+	done chan struct{}
+
 	// User ID derived from authentication
 	UserID uint
 
+	// Session (device) the connection was opened with, used to kick it on logout
+	SessionID uint
+
 	// Database connection for persistence/deletion
 	DB *gorm.DB
 
@@ -46,6 +53,18 @@ type Client struct {
 	mu           sync.Mutex
 }
 
+func NewClient(hub *Hub, conn *websocket.Conn, userID, sessionID uint, db *gorm.DB) *Client {
+	return &Client{
+		Hub:       hub,
+		Conn:      conn,
+		Send:      make(chan []byte, 256),
+		done:      make(chan struct{}),
+		UserID:    userID,
+		SessionID: sessionID,
+		DB:        db,
+	}
+}
+
 // WSMessage defines the structure of messages sent over WebSocket
 type WSMessage struct {
 	Type        string          `json:"type"` // 'chat', 'read', 'typing'
@@ -89,13 +108,19 @@ func (c *Client) WritePump() {
 	}()
 	for {
 		select {
-		case message, ok := <-c.Send:
+		case <-c.done:
+			// The hub let go of the client. Flush what was queued before
+			// (e.g. session_revoked), then close the connection, which ends ReadPump.
 			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if !ok {
-				// The hub closed the channel.
-				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
+			for n := len(c.Send); n > 0; n-- {
+				if err := c.Conn.WriteMessage(websocket.TextMessage, <-c.Send); err != nil {
+					return
+				}
 			}
+			c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+			return
+		case message := <-c.Send:
+			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
 
 			w, err := c.Conn.NextWriter(websocket.TextMessage)
 			if err != nil {
@@ -183,14 +208,43 @@ func (c *Client) broadcastRoomStatus(roomID uint, inRoom bool) {
 		"in_room":      inRoom,
 	})
 
-	// Send to all other participants
+	// Send to all other participants, except those in a block with this user
+	blocked := c.Hub.blockedWith(c.UserID)
 	for _, p := range room.Participants {
-		if p.UserID != c.UserID {
+		if p.UserID != c.UserID && !blocked[p.UserID] {
 			c.Hub.SendToUser(p.UserID, statusJSON)
 		}
 	}
 }
 
+// sendError tells this client that one of its events was rejected
+func (c *Client) sendError(code string, message string, roomID uint) {
+	errorJSON, _ := json.Marshal(map[string]interface{}{
+		"type":         "error",
+		"code":         code,
+		"message":      message,
+		"chat_room_id": roomID,
+	})
+	select {
+	case c.Send <- errorJSON:
+	default:
+	}
+}
+
+// send queues a message for this client. It waits up to writeWait for room in
+// the buffer and gives up once the client is disconnected.
+func (c *Client) send(message []byte) {
+	timer := time.NewTimer(writeWait)
+	defer timer.Stop()
+
+	select {
+	case c.Send <- message:
+	case <-c.done:
+	case <-timer.C:
+		log.Printf("Dropped message for user %d: send buffer full", c.UserID)
+	}
+}
+
 func (c *Client) processChatMessage(wsMsg *WSMessage) {
 	// 1. Find Chat Room and Participants (needed to know who to send to)
 	// 1. Find Chat Room and Participants (needed to know who to send to)
@@ -201,15 +255,6 @@ func (c *Client) processChatMessage(wsMsg *WSMessage) {
 		return
 	}
 
-	// Restore soft-deleted participants (logic: if new message comes, chat is active again)
-	for _, p := range room.Participants {
-		if p.DeletedAt.Valid {
-			// Restore
-			c.DB.Unscoped().Model(&p).Update("deleted_at", nil)
-			log.Printf("Restored participation for user %d in room %d", p.UserID, room.ID)
-		}
-	}
-
 	// 2. Determine Recipient
 	var recipientID uint
 	for _, p := range room.Participants {
@@ -219,6 +264,22 @@ func (c *Client) processChatMessage(wsMsg *WSMessage) {
 		}
 	}
 
+	// Messages between blocked users are dropped, not stored
+	if recipientID != 0 && c.Hub.blockedWith(c.UserID)[recipientID] {
+		log.Printf("Dropped message from user %d to user %d: blocked", c.UserID, recipientID)
+		c.sendError("blocked", "You cannot send messages to this user", wsMsg.ChatRoomID)
+		return
+	}
+
+	// Restore soft-deleted participants (logic: if new message comes, chat is active again)
+	for _, p := range room.Participants {
+		if p.DeletedAt.Valid {
+			// Restore
+			c.DB.Unscoped().Model(&p).Update("deleted_at", nil)
+			log.Printf("Restored participation for user %d in room %d", p.UserID, room.ID)
+		}
+	}
+
 	// 3. Check if Recipient is currently IN the room (viewing the chat screen)
 	recipientInRoom := false
 	if recipientID != 0 {
@@ -227,24 +288,37 @@ func (c *Client) processChatMessage(wsMsg *WSMessage) {
 
 	// 4. Fetch Sender Info for JSON payload
 	var sender models.User
-	if err := c.DB.First(&sender, c.UserID).Error; err != nil {
+	if err := c.DB.Select(models.UserSummaryColumns).First(&sender, c.UserID).Error; err != nil {
 		log.Printf("Error fetching sender info: %v", err)
 	}
 
 	log.Printf("Processing message. RecipientID: %d, InRoom: %v", recipientID, recipientInRoom)
 
 	if recipientInRoom {
-		// CASE 1: Recipient IS in room - Direct delivery, NO database save (true ephemeral)
-		// Create a temporary message object for JSON (no DB save)
+		// CASE 1: Recipient IS in room - Direct delivery. The message is stored
+		// already read and hidden, so it never shows up in the chat again but
+		// can be reported until the message purger removes it.
+		delivered := models.Message{
+			ChatRoomID:  wsMsg.ChatRoomID,
+			SenderID:    c.UserID,
+			Content:     wsMsg.Content,
+			IsRead:      true,
+			ProductInfo: string(wsMsg.Product),
+			DeletedAt:   gorm.DeletedAt{Time: time.Now(), Valid: true},
+		}
+		if err := c.DB.Omit("Sender").Create(&delivered).Error; err != nil {
+			log.Printf("Error keeping delivered message for reports: %v", err)
+		}
+
 		tempMsg := map[string]interface{}{
-			"id":           0, // No DB ID since not saved
+			"id":           delivered.ID, // Used to report the message
 			"chat_room_id": wsMsg.ChatRoomID,
 			"sender_id":    c.UserID,
 			"content":      wsMsg.Content,
 			"media_type":   "text",
 			"is_read":      true, // Already read since recipient is in room
 			"created_at":   time.Now(),
-			"sender":       sender,
+			"sender":       sender.Summary(),
 			"product":      wsMsg.Product,
 		}
 
@@ -260,9 +334,9 @@ func (c *Client) processChatMessage(wsMsg *WSMessage) {
 		c.Hub.SendToUser(recipientID, responseJSON)
 
 		// Also send to sender so their UI shows the message with is_read=true
-		c.Send <- responseJSON
+		c.send(responseJSON)
 
-		log.Printf("Message sent directly to recipient (ephemeral, no DB save)")
+		log.Printf("Message sent directly to recipient (kept hidden for reports)")
 	} else {
 		// CASE 2: Recipient NOT in room - Save to database for later retrieval
 		newMsg := models.Message{
@@ -289,7 +363,7 @@ func (c *Client) processChatMessage(wsMsg *WSMessage) {
 		})
 
 		// Echo back to sender so they see it (Unread) and have the ID for future read receipt
-		c.Send <- responseJSON
+		c.send(responseJSON)
 
 		// NEW: Always send to recipient if they are online, even if not "in room"
 		// This ensures real-time updates for list view or if they are actually in room (false negative)
@@ -324,13 +398,13 @@ func (c *Client) processReadReceipt(wsMsg *WSMessage) {
 		senderID := msg.SenderID
 		chatRoomID := msg.ChatRoomID
 
-		// Hard Delete the message
-		if err := c.DB.Unscoped().Delete(&models.Message{}, wsMsg.MessageID).Error; err != nil {
+		// Soft delete: hidden from the chat, kept for reports until the message purger removes it
+		if err := c.DB.Delete(&models.Message{}, wsMsg.MessageID).Error; err != nil {
 			log.Printf("Error deleting read message: %v", err)
 			return // Don't notify if delete failed? or notify regardless? Let's return.
 		}
 
-		log.Printf("Message %d read by user %d --> DELETED from DB", wsMsg.MessageID, c.UserID)
+		log.Printf("Message %d read by user %d --> hidden from the chat", wsMsg.MessageID, c.UserID)
 
 		// Notify the Sender that their message was read
 		if senderID != c.UserID { // Should always be true but good to check
@@ -357,7 +431,7 @@ func (c *Client) SendUnreadMessages() {
 	// AND Message.IsRead = false
 
 	// PRELOAD Sender so the client can display who sent it!
-	err := c.DB.Preload("Sender").Joins("JOIN chat_participants cp ON cp.chat_room_id = messages.chat_room_id").
+	err := c.DB.Preload("Sender", models.PublicSender).Joins("JOIN chat_participants cp ON cp.chat_room_id = messages.chat_room_id").
 		Where("cp.user_id = ? AND messages.sender_id != ? AND messages.is_read = ?", c.UserID, c.UserID, false).
 		Find(&unreadMessages).Error
 
@@ -375,19 +449,19 @@ func (c *Client) SendUnreadMessages() {
 				"sender_id":    msg.SenderID,
 				"chat_room_id": msg.ChatRoomID,
 			})
-			c.Send <- responseJSON
+			c.send(responseJSON)
 		}
 	}
 }
 
 // SendUnreadMessagesForRoom fetches and delivers unread messages for a specific room
 // This is called when user joins a room to get messages they missed while not in the room
-// After sending, messages are DELETED from the database (ephemeral behavior)
+// After sending, messages are hidden from the chat (soft-deleted) and later purged
 func (c *Client) SendUnreadMessagesForRoom(roomID uint) {
 	var unreadMessages []models.Message
 
 	// Fetch unread messages for this specific room
-	err := c.DB.Preload("Sender").
+	err := c.DB.Preload("Sender", models.PublicSender).
 		Where("chat_room_id = ? AND sender_id != ? AND is_read = ?", roomID, c.UserID, false).
 		Order("created_at ASC").
 		Find(&unreadMessages).Error
@@ -410,7 +484,7 @@ func (c *Client) SendUnreadMessagesForRoom(roomID uint) {
 				"sender_id":    msg.SenderID,
 				"chat_room_id": msg.ChatRoomID,
 			})
-			c.Send <- responseJSON
+			c.send(responseJSON)
 			messageIDs = append(messageIDs, msg.ID)
 
 			// Notify sender that their message was read/delivered
@@ -425,10 +499,10 @@ func (c *Client) SendUnreadMessagesForRoom(roomID uint) {
 
 		// Delete all fetched messages from database (ephemeral)
 		if len(messageIDs) > 0 {
-			if err := c.DB.Unscoped().Where("id IN ?", messageIDs).Delete(&models.Message{}).Error; err != nil {
+			if err := c.DB.Where("id IN ?", messageIDs).Delete(&models.Message{}).Error; err != nil {
 				log.Printf("Error deleting messages after fetch: %v", err)
 			} else {
-				log.Printf("Deleted %d messages from room %d after delivery to user %d", len(messageIDs), roomID, c.UserID)
+				log.Printf("Hid %d messages in room %d after delivery to user %d", len(messageIDs), roomID, c.UserID)
 			}
 		}
 	}
@@ -18,6 +18,9 @@ type Hub struct {
 	// Unregister requests from clients.
 	Unregister chan *Client
 
+	// Server-side disconnects, handled by the hub loop like Unregister
+	disconnects chan disconnectRequest
+
 	// Inbound messages from the clients.
 	Broadcast chan []byte
 
@@ -26,6 +29,16 @@ type Hub struct {
 
 	// Mutex to protect the userClients map
 	mutex sync.Mutex
+
+	// BlockedWith returns the users that have a block with userID in either
+	// direction. Presence and chat are not shared between them. Optional.
+	BlockedWith func(userID uint) map[uint]bool
+}
+
+// disconnectRequest asks the hub loop to notify and unregister the matching clients
+type disconnectRequest struct {
+	match  func(*Client) bool
+	reason string
 }
 
 func NewHub() *Hub {
@@ -33,6 +46,7 @@ func NewHub() *Hub {
 		Broadcast:   make(chan []byte),
 		Register:    make(chan *Client),
 		Unregister:  make(chan *Client),
+		disconnects: make(chan disconnectRequest),
 		clients:     make(map[*Client]bool),
 		userClients: make(map[uint][]*Client),
 	}
@@ -45,24 +59,33 @@ func (h *Hub) Run() {
 			h.clients[client] = true
 			h.limitRegister(client)
 		case client := <-h.Unregister:
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.Send)
-				h.limitUnregister(client)
-			}
+			h.remove(client)
+		case req := <-h.disconnects:
+			h.disconnectMatching(req)
 		case message := <-h.Broadcast:
 			for client := range h.clients {
 				select {
 				case client.Send <- message:
 				default:
-					close(client.Send)
-					delete(h.clients, client)
+					h.remove(client)
 				}
 			}
 		}
 	}
 }
 
+// remove unregisters the client. Only the hub loop calls it. Send stays open
+// because ReadPump may still be handling a frame and writing to it; closing done
+// makes WritePump flush, close the connection and so end ReadPump.
+func (h *Hub) remove(client *Client) {
+	if _, ok := h.clients[client]; !ok {
+		return
+	}
+	delete(h.clients, client)
+	h.limitUnregister(client)
+	close(client.done)
+}
+
 // limitRegister registers a client to the specific user map
 func (h *Hub) limitRegister(client *Client) {
 	h.mutex.Lock()
@@ -85,27 +108,35 @@ func (h *Hub) limitRegister(client *Client) {
 	// NOTE: We do NOT update database for online status anymore
 	// Online status is purely in-memory via WebSocket hub
 
-	// 2. Broadcast Status Change (Online)
-	statusJSON, _ := json.Marshal(map[string]interface{}{
-		"type":      "user_status",
-		"user_id":   client.UserID,
-		"is_online": true,
-	})
+	// The block lookup hits the database, so it runs outside the hub loop
 	go func() {
-		h.Broadcast <- statusJSON
-	}()
+		// Blocked users do not see each other online
+		blocked := h.blockedWith(client.UserID)
 
-	// 3. Send Initial Online List to THIS Client
-	// This fixes the bug where only one user sees the other, but not vice versa.
-	if len(onlineUserIDs) > 0 {
-		initialStatusJSON, _ := json.Marshal(map[string]interface{}{
-			"type":     "online_users_list",
-			"user_ids": onlineUserIDs,
+		// 2. Broadcast Status Change (Online)
+		statusJSON, _ := json.Marshal(map[string]interface{}{
+			"type":      "user_status",
+			"user_id":   client.UserID,
+			"is_online": true,
 		})
-		go func() {
-			client.Send <- initialStatusJSON
-		}()
-	}
+		h.sendToAllExcept(blocked, statusJSON)
+
+		// 3. Send Initial Online List to THIS Client
+		// This fixes the bug where only one user sees the other, but not vice versa.
+		visibleUserIDs := make([]uint, 0, len(onlineUserIDs))
+		for _, userID := range onlineUserIDs {
+			if !blocked[userID] {
+				visibleUserIDs = append(visibleUserIDs, userID)
+			}
+		}
+		if len(visibleUserIDs) > 0 {
+			initialStatusJSON, _ := json.Marshal(map[string]interface{}{
+				"type":     "online_users_list",
+				"user_ids": visibleUserIDs,
+			})
+			h.sendIfConnected(client, initialStatusJSON)
+		}
+	}()
 
 	// NOTE: We do NOT call SendUnreadMessages here anymore.
 	// Unread messages are now sent only when the user joins a specific room
@@ -142,8 +173,10 @@ func (h *Hub) limitUnregister(client *Client) {
 			"is_online": false,
 		})
 
+		// Looked up outside the lock held by the caller
+		userID := client.UserID
 		go func() {
-			h.Broadcast <- statusJSON
+			h.sendToAllExcept(h.blockedWith(userID), statusJSON)
 		}()
 
 		log.Printf("User %d disconnected (Offline)", client.UserID)
@@ -157,18 +190,59 @@ func (h *Hub) SendToUser(userID uint, message []byte) {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 
+	// Slow clients miss the message; only the hub loop closes connections
 	if clients, ok := h.userClients[userID]; ok {
 		for _, client := range clients {
 			select {
 			case client.Send <- message:
 			default:
-				close(client.Send)
-				delete(h.clients, client)
 			}
 		}
 	}
 }
 
+// sendIfConnected sends to one client unless it was unregistered in the meantime
+func (h *Hub) sendIfConnected(client *Client, message []byte) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, c := range h.userClients[client.UserID] {
+		if c == client {
+			select {
+			case client.Send <- message:
+			default:
+			}
+			return
+		}
+	}
+}
+
+// sendToAllExcept sends a message to every connected user not in exclude
+func (h *Hub) sendToAllExcept(exclude map[uint]bool, message []byte) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for userID, clients := range h.userClients {
+		if exclude[userID] {
+			continue
+		}
+		for _, client := range clients {
+			select {
+			case client.Send <- message:
+			default:
+			}
+		}
+	}
+}
+
+// blockedWith returns the users blocked with userID, or none when BlockedWith is not set
+func (h *Hub) blockedWith(userID uint) map[uint]bool {
+	if h.BlockedWith == nil {
+		return map[uint]bool{}
+	}
+	return h.BlockedWith(userID)
+}
+
 // IsUserInRoom checks if a user has any active connection in the specified room
 func (h *Hub) IsUserInRoom(userID uint, roomID uint) bool {
 	h.mutex.Lock()
@@ -220,3 +294,51 @@ func (h *Hub) IsUserOnline(userID uint) bool {
 	clients, ok := h.userClients[userID]
 	return ok && len(clients) > 0
 }
+
+// DisconnectSession closes every connection that was opened with the given session
+func (h *Hub) DisconnectSession(sessionID uint, reason string) {
+	h.disconnect(func(client *Client) bool {
+		return client.SessionID == sessionID
+	}, reason)
+}
+
+// DisconnectUser closes all connections of a user
+func (h *Hub) DisconnectUser(userID uint, reason string) {
+	h.disconnect(func(client *Client) bool {
+		return client.UserID == userID
+	}, reason)
+}
+
+// DisconnectOtherSessions closes all connections of a user except those of keepSessionID
+func (h *Hub) DisconnectOtherSessions(userID uint, keepSessionID uint, reason string) {
+	h.disconnect(func(client *Client) bool {
+		return client.UserID == userID && client.SessionID != keepSessionID
+	}, reason)
+}
+
+// disconnect notifies the matching clients and unregisters them. It runs in the
+// hub loop, so a client cannot unregister between the two steps. Unregistering
+// closes client.done, which makes WritePump flush the notification, send a close
+// frame and close the connection.
+func (h *Hub) disconnect(match func(*Client) bool, reason string) {
+	h.disconnects <- disconnectRequest{match: match, reason: reason}
+}
+
+func (h *Hub) disconnectMatching(req disconnectRequest) {
+	revokedJSON, _ := json.Marshal(map[string]interface{}{
+		"type":   "session_revoked",
+		"reason": req.reason,
+	})
+
+	for client := range h.clients {
+		if !req.match(client) {
+			continue
+		}
+		select {
+		case client.Send <- revokedJSON:
+		default:
+		}
+		h.remove(client)
+		log.Printf("User %d disconnected by server (%s)", client.UserID, req.reason)
+	}
+}
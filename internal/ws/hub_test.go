@@ -0,0 +1,74 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDisconnectKeepsSendOpenForReadPump(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	client := NewClient(hub, nil, 1, 10, nil)
+	hub.Register <- client
+	hub.DisconnectUser(1, "logout")
+
+	select {
+	case <-client.done:
+	case <-time.After(time.Second):
+		t.Fatal("client was not disconnected")
+	}
+
+	// The notification is queued for WritePump to flush, possibly after presence events
+	revoked := false
+	for n := len(client.Send); n > 0; n-- {
+		var event map[string]interface{}
+		if err := json.Unmarshal(<-client.Send, &event); err != nil {
+			t.Fatal(err)
+		}
+		if event["type"] == "session_revoked" && event["reason"] == "logout" {
+			revoked = true
+		}
+	}
+	if !revoked {
+		t.Fatal("session_revoked was not queued")
+	}
+
+	// ReadPump may still be handling a frame; its writes must not panic or block
+	client.sendError("rate_limited", "slow down", 0)
+	for i := 0; i < cap(client.Send)+1; i++ {
+		client.send([]byte(`{"type":"chat"}`))
+	}
+
+	// Unregistering after the disconnect is a no-op
+	hub.Unregister <- client
+	if hub.IsUserOnline(1) {
+		t.Fatal("user is still online")
+	}
+}
+
+func TestDisconnectSessionLeavesOtherSessions(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	kicked := NewClient(hub, nil, 1, 10, nil)
+	kept := NewClient(hub, nil, 1, 11, nil)
+	hub.Register <- kicked
+	hub.Register <- kept
+	hub.DisconnectSession(10, "logout")
+
+	select {
+	case <-kicked.done:
+	case <-time.After(time.Second):
+		t.Fatal("session was not disconnected")
+	}
+	select {
+	case <-kept.done:
+		t.Fatal("other session was disconnected")
+	default:
+	}
+	if !hub.IsUserOnline(1) {
+		t.Fatal("user went offline with a session still connected")
+	}
+}
@@ -0,0 +1,79 @@
+package ws
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// ticket is a short-lived, single-use credential for opening a websocket.
+// It keeps the long-lived bearer token out of URLs (and therefore out of access logs).
+type ticket struct {
+	UserID    uint
+	SessionID uint
+	IP        string
+	ExpiresAt time.Time
+}
+
+// TicketStore keeps issued tickets in memory until they are redeemed or expire
+type TicketStore struct {
+	ttl time.Duration
+
+	mutex   sync.Mutex
+	tickets map[string]ticket
+}
+
+func NewTicketStore(ttl time.Duration) *TicketStore {
+	return &TicketStore{
+		ttl:     ttl,
+		tickets: make(map[string]ticket),
+	}
+}
+
+// Issue creates a ticket bound to the user, session and client IP
+func (s *TicketStore) Issue(userID, sessionID uint, ip string) (string, time.Time, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", time.Time{}, err
+	}
+	value := hex.EncodeToString(bytes)
+	expiresAt := time.Now().Add(s.ttl)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// Drop expired tickets that were never redeemed
+	now := time.Now()
+	for k, t := range s.tickets {
+		if now.After(t.ExpiresAt) {
+			delete(s.tickets, k)
+		}
+	}
+
+	s.tickets[value] = ticket{
+		UserID:    userID,
+		SessionID: sessionID,
+		IP:        ip,
+		ExpiresAt: expiresAt,
+	}
+	return value, expiresAt, nil
+}
+
+// Redeem consumes a ticket. It fails if the ticket is unknown, expired, already
+// used or presented from a different IP than the one it was issued to.
+func (s *TicketStore) Redeem(value string, ip string) (userID uint, sessionID uint, ok bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	t, found := s.tickets[value]
+	if !found {
+		return 0, 0, false
+	}
+	delete(s.tickets, value) // Single use, even if the checks below fail
+
+	if time.Now().After(t.ExpiresAt) || t.IP != ip {
+		return 0, 0, false
+	}
+	return t.UserID, t.SessionID, true
+}
@@ -5,13 +5,23 @@ import (
 	"log"
 	"meetup_backend/config"
 	"meetup_backend/handlers"
+	"meetup_backend/internal/account"
+	"meetup_backend/internal/chat"
+	"meetup_backend/internal/geo"
+	"meetup_backend/internal/mailer"
+	"meetup_backend/internal/outcome"
+	"meetup_backend/internal/payment"
+	"meetup_backend/internal/points"
+	"meetup_backend/internal/sms"
+	"meetup_backend/internal/token"
+	"meetup_backend/internal/topup"
 	"meetup_backend/internal/ws"
 	"meetup_backend/middleware"
 	"meetup_backend/utils"
-	"os"
+	"strings"
+	"time"
 
 	"github.com/gofiber/contrib/websocket"
-	"github.com/golang-jwt/jwt/v5"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/driver/mysql"
@@ -65,6 +75,9 @@ func main() {
 	sqlDb.SetMaxOpenConns(100)
 	sqlDb.SetMaxIdleConns(10)
 
+	// Password hashing strength (existing hashes are upgraded on next login)
+	utils.PasswordCost = cfg.BcryptCost
+
 	// Run Migrations
 
 	// Parse command line flags
@@ -98,14 +111,111 @@ func main() {
 
 	// WebSocket Configuration
 	hub := ws.NewHub()
+	hub.BlockedWith = func(userID uint) map[uint]bool {
+		return utils.BlockedWith(db, userID)
+	}
 	go hub.Run()
 
-	authHandler := handlers.NewAuthHandler(db)
-	chatHandler := handlers.NewChatHandler(hub, db)
-	userHandler := handlers.NewUserHandler(db)
-	productHandler := handlers.NewProductHandler(db)
+	// Mailer Configuration
+	var mail mailer.Mailer
+	if cfg.MailDriver == "smtp" {
+		mail = mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+	} else {
+		mail = mailer.NewFakeMailer(cfg.MailFakeDir)
+	}
+
+	// SMS Gateway (the fake delivers nothing; plug other providers in through sms.Gateway)
+	var smsGateway sms.Gateway
+	if cfg.SMSDriver == "twilio" {
+		smsGateway = sms.NewTwilioGateway(cfg.TwilioAccountSID, cfg.TwilioAuthToken, cfg.TwilioFrom)
+	} else {
+		smsGateway = sms.NewFakeGateway()
+	}
+
+	// Token Service (asymmetric signing keys stored in DB, rotated in the background)
+	tokens, err := token.NewService(db, token.Options{
+		Algorithm:        cfg.JWTAlgorithm,
+		Issuer:           cfg.JWTIssuer,
+		Audience:         cfg.JWTAudience,
+		RotationInterval: cfg.JWTKeyRotationInterval,
+		VerifyGrace:      cfg.JWTKeyGracePeriod,
+	})
+	if err != nil {
+		log.Fatal("Failed to initialize token service:", err)
+	}
+	go tokens.Run(10 * time.Minute)
+
+	// Gazetteer for offline reverse geocoding (city/province of users and products)
+	gazetteer, err := geo.Load(cfg.GazetteerPath)
+	if err != nil {
+		log.Printf("Gazetteer not loaded, locations will have no city/province: %v", err)
+		gazetteer = geo.New()
+	} else {
+		log.Printf("Loaded %d areas from %s", gazetteer.Len(), cfg.GazetteerPath)
+	}
+
+	// Points ledger, the only place where balances change
+	pointsService := points.NewService(db)
+
+	// Payment Provider for point top-ups. The fake confirms any payment on request,
+	// so it is only used when asked for; plug a gateway in through payment.Provider.
+	var topUps *topup.Service
+	switch cfg.PaymentProvider {
+	case "fake":
+		log.Println("Using the fake payment provider, top-ups are free (development only)")
+		topUps = topup.NewService(db, payment.NewFakeProvider(cfg.PaymentWebhookSecret, cfg.AppURL), cfg.PaymentOrderExpiration)
+		go topUps.Run(time.Minute)
+	case "":
+		log.Println("PAYMENT_PROVIDER not set, point top-ups are disabled")
+	default:
+		log.Fatalf("Unknown PAYMENT_PROVIDER %q", cfg.PaymentProvider)
+	}
+
+	// Meetup outcomes: settles meetups once both sides reported or the window ends
+	outcomes := outcome.NewService(db, hub, cfg.MeetupOutcomeWindow)
+	go outcomes.Run(10 * time.Minute)
+
+	// Anonymise accounts whose deletion grace period has ended
+	go account.NewPurger(db).Run(time.Hour)
+
+	// Remove read messages once they can no longer be reported
+	go chat.NewMessagePurger(db, cfg.MessageReportWindow).Run(time.Hour)
+
+	// Public keys for other services to verify our tokens
+	app.Get("/.well-known/jwks.json", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+		return c.JSON(tokens.JWKS())
+	})
+
+	authMiddleware := utils.AuthMiddleware(db, tokens)
+	// scoped also accepts a personal API key (X-API-Key) that holds the scope
+	scoped := func(scope string) fiber.Handler {
+		return utils.AuthMiddleware(db, tokens, scope)
+	}
+	requireVerified := utils.RequireVerifiedEmail(db, cfg.RequireVerifiedEmail)
+	optionalAuth := utils.OptionalAuth(db, tokens) // Public routes that hide blocked users when signed in
+
+	authHandler := handlers.NewAuthHandler(db, cfg, hub, mail, tokens)
+	twoFactorHandler := handlers.NewTwoFactorHandler(db, cfg)
+	wsTickets := ws.NewTicketStore(30 * time.Second)
+	chatHandler := handlers.NewChatHandler(hub, db, wsTickets)
+	userHandler := handlers.NewUserHandler(db, gazetteer)
+	phoneHandler := handlers.NewPhoneHandler(db, cfg, smsGateway)
+	apiKeyHandler := handlers.NewAPIKeyHandler(db)
+	productHandler := handlers.NewProductHandler(db, gazetteer, hub)
 	categoryHandler := handlers.NewCategoryHandler(db)
-	uploadHandler := handlers.NewUploadHandler()
+	uploadHandler := handlers.NewUploadHandler(db)
+	adminHandler := handlers.NewAdminHandler(db, hub, pointsService)
+	accountHandler := handlers.NewAccountHandler(db, cfg, hub)
+	geoHandler := handlers.NewGeoHandler(gazetteer)
+	followHandler := handlers.NewFollowHandler(db)
+	blockHandler := handlers.NewBlockHandler(db, hub)
+	reportHandler := handlers.NewReportHandler(db, cfg)
+	moderationHandler := handlers.NewModerationHandler(db, hub, outcomes)
+	meetupHandler := handlers.NewMeetupHandler(db, cfg, outcomes)
+	reviewHandler := handlers.NewReviewHandler(db, cfg)
+	pointsHandler := handlers.NewPointsHandler(db)
+	topUpHandler := handlers.NewTopUpHandler(db, topUps)
 
 	// Serve Static Files (Uploads)
 	app.Static("/uploads", "./uploads")
@@ -117,21 +227,84 @@ func main() {
 	auth := api.Group("/auth")
 	auth.Post("/register", authHandler.Register)
 	auth.Post("/login", authHandler.Login)
-
-	// User Routes (Protected)
-	users := api.Group("/users", utils.AuthMiddleware)
-	users.Get("/search", userHandler.SearchUsers)
+	auth.Post("/login/2fa", authHandler.LoginTwoFactor)
+	auth.Post("/refresh", authHandler.Refresh)
+	auth.Get("/verify", authHandler.VerifyEmail)
+	auth.Post("/verify/resend", authMiddleware, authHandler.ResendVerification)
+	auth.Post("/forgot-password", authHandler.ForgotPassword)
+	auth.Post("/reset-password", authHandler.ResetPassword)
+	auth.Post("/change-password", authMiddleware, authHandler.ChangePassword)
+	auth.Post("/logout", authMiddleware, authHandler.Logout)
+	auth.Post("/logout-all", authMiddleware, authHandler.LogoutAll)
+	auth.Get("/sessions", authMiddleware, authHandler.GetSessions)
+	auth.Delete("/sessions/:id", authMiddleware, authHandler.RevokeSession)
+
+	// Social Login (OpenID Connect)
+	auth.Get("/oidc/providers", authHandler.ListOIDCProviders)
+	auth.Get("/oidc/:provider/login", authHandler.OIDCLogin)
+	auth.Get("/oidc/:provider/callback", authHandler.OIDCCallback)
+	auth.Post("/oidc/:provider/callback", authHandler.OIDCCallback)
+	auth.Post("/oidc/:provider/link", authMiddleware, authHandler.OIDCLink)
+	auth.Get("/identities", authMiddleware, authHandler.GetIdentities)
+	auth.Delete("/identities/:provider", authMiddleware, authHandler.UnlinkIdentity)
+
+	// Two-Factor Authentication Routes (Protected)
+	twoFactor := auth.Group("/2fa", authMiddleware)
+	twoFactor.Post("/setup", twoFactorHandler.Setup)
+	twoFactor.Post("/confirm", twoFactorHandler.Confirm)
+	twoFactor.Post("/disable", twoFactorHandler.Disable)
+	twoFactor.Post("/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+
+	// User Routes (Protected, except the public profile)
+	users := api.Group("/users")
+	users.Get("/search", authMiddleware, userHandler.SearchUsers)
+	users.Get("/me", authMiddleware, userHandler.GetMe)
+	users.Patch("/me", authMiddleware, userHandler.UpdateMe)
+	users.Post("/me/avatar", authMiddleware, userHandler.UploadAvatar)
+	users.Post("/me/phone", authMiddleware, phoneHandler.RequestCode)
+	users.Post("/me/phone/verify", authMiddleware, phoneHandler.VerifyCode)
+	users.Delete("/me/phone", authMiddleware, phoneHandler.RemovePhone)
+	users.Put("/me/chat-requirements", authMiddleware, phoneHandler.UpdateChatRequirements)
+	users.Get("/me/export", authMiddleware, accountHandler.ExportData)
+	users.Post("/me/deletion", authMiddleware, accountHandler.RequestDeletion)
+	users.Delete("/me/deletion", authMiddleware, accountHandler.CancelDeletion)
+	users.Get("/me/blocked", authMiddleware, blockHandler.GetBlockedUsers)
+	users.Get("/:id", userHandler.GetProfile)               // Public
+	users.Get("/:id/followers", followHandler.GetFollowers) // Public
+	users.Get("/:id/following", followHandler.GetFollowing) // Public
+	users.Get("/:id/reviews", reviewHandler.GetUserReviews) // Public
+	users.Post("/:id/reviews", authMiddleware, reviewHandler.CreateReview)
+	users.Post("/:id/follow", authMiddleware, followHandler.Follow)
+	users.Delete("/:id/follow", authMiddleware, followHandler.Unfollow)
+	users.Post("/:id/block", authMiddleware, blockHandler.BlockUser)
+	users.Delete("/:id/block", authMiddleware, blockHandler.UnblockUser)
+	users.Post("/:id/report", authMiddleware, reportHandler.ReportUser)
+
+	// Feed (Protected): new products from followed sellers
+	api.Get("/feed", authMiddleware, followHandler.GetFeed)
+
+	// API Key Management (Protected, session only)
+	apiKeys := api.Group("/api-keys", authMiddleware)
+	apiKeys.Get("/", apiKeyHandler.ListKeys)
+	apiKeys.Post("/", apiKeyHandler.CreateKey)
+	apiKeys.Delete("/:id", apiKeyHandler.RevokeKey)
+
+	// Geo Routes (Public)
+	api.Get("/geo/areas", geoHandler.SearchAreas)
+	api.Get("/geo/reverse", geoHandler.ReverseGeocode)
 
 	// Category Routes
 	api.Get("/categories", categoryHandler.GetCategories)
 
 	// Product Routes
 	products := api.Group("/products")
-	products.Get("/", productHandler.GetAllProducts)                            // Public
-	products.Get("/:id", productHandler.GetProduct)                             // Public
-	products.Post("/", utils.AuthMiddleware, productHandler.CreateProduct)      // Protected
-	products.Put("/:id", utils.AuthMiddleware, productHandler.UpdateProduct)    // Protected
-	products.Delete("/:id", utils.AuthMiddleware, productHandler.DeleteProduct) // Protected
+	products.Get("/", optionalAuth, productHandler.GetAllProducts)                                      // Public
+	products.Get("/nearby", optionalAuth, productHandler.GetNearbyProducts)                             // Public
+	products.Get("/:id", optionalAuth, productHandler.GetProduct)                                       // Public
+	products.Post("/", scoped(utils.ScopeProductsWrite), requireVerified, productHandler.CreateProduct) // Protected, verified email
+	products.Put("/:id", scoped(utils.ScopeProductsWrite), productHandler.UpdateProduct)                // Protected
+	products.Delete("/:id", scoped(utils.ScopeProductsWrite), productHandler.DeleteProduct)             // Protected
+	products.Post("/:id/report", authMiddleware, reportHandler.ReportProduct)                           // Protected
 
 	// My Products (Protected) - Must be before /:id to avoid conflict if logic wasn't strict (though here it's fine as "my-products" is not int)
 	// Actually, better to put it under a separate group or ensure no conflict.
@@ -140,20 +313,70 @@ func main() {
 	// /api/my-products is cleaner if we move it out of /products or just put above.
 	// User requested "GET /api/my-products", so let's register it at root api group or under /products/my (which would be /api/products/my)
 	// The plan said: "Register the new route GET /api/my-products (protected)"
-	api.Get("/my-products", utils.AuthMiddleware, productHandler.GetMyProducts)
+	api.Get("/my-products", scoped(utils.ScopeProductsRead), productHandler.GetMyProducts)
 
 	// Upload Route (Protected)
-	api.Post("/upload", utils.AuthMiddleware, uploadHandler.UploadImage)
-	api.Post("/upload/multiple", utils.AuthMiddleware, uploadHandler.UploadMultipleImages)
+	api.Post("/upload", scoped(utils.ScopeProductsWrite), uploadHandler.UploadImage)
+	api.Post("/upload/multiple", scoped(utils.ScopeProductsWrite), uploadHandler.UploadMultipleImages)
 
 	// Chat Routes (Protected)
-	chat := api.Group("/chat", utils.AuthMiddleware)
-	chat.Get("/rooms", chatHandler.GetMyChats) // Get list of chats
-	chat.Post("/private", chatHandler.InitPrivateChat)
-	chat.Get("/room/:roomID/messages", chatHandler.GetChatMessages)
-	chat.Get("/room/:roomID/status", chatHandler.GetRoomStatus)
-	chat.Delete("/room/:roomID", chatHandler.DeleteChat) // Delete chat route
-	chat.Post("/toggle-ready", chatHandler.ToggleMeetupReady)
+	chat := api.Group("/chat")
+	chat.Get("/rooms", scoped(utils.ScopeChatRead), chatHandler.GetMyChats) // Get list of chats
+	chat.Post("/private", scoped(utils.ScopeChatWrite), chatHandler.InitPrivateChat)
+	chat.Get("/room/:roomID/messages", scoped(utils.ScopeChatRead), chatHandler.GetChatMessages)
+	chat.Get("/room/:roomID/status", scoped(utils.ScopeChatRead), chatHandler.GetRoomStatus)
+	chat.Delete("/room/:roomID", scoped(utils.ScopeChatWrite), chatHandler.DeleteChat) // Delete chat route
+	chat.Post("/messages/:id/report", authMiddleware, reportHandler.ReportMessage)
+	// Confirming a meetup spends points, so it needs a real login session (no API keys)
+	chat.Post("/toggle-ready", authMiddleware, requireVerified, chatHandler.ToggleMeetupReady)
+
+	// Meetups (Protected): confirmed meetups, reviewed through /api/users/:id/reviews
+	api.Get("/meetups", authMiddleware, meetupHandler.ListMeetups)
+	api.Post("/meetups/:id/outcome", authMiddleware, meetupHandler.ReportOutcome)
+
+	// Points (Protected)
+	api.Get("/points/history", authMiddleware, pointsHandler.GetHistory)
+	api.Get("/points/orders", authMiddleware, topUpHandler.ListOrders)
+	api.Get("/points/orders/:id", authMiddleware, topUpHandler.GetOrder)
+	if topUps != nil {
+		api.Get("/points/packages", topUpHandler.GetPackages) // Public
+		api.Post("/points/checkout", authMiddleware, requireVerified, topUpHandler.Checkout)
+
+		// Payment Webhooks (signed by the provider, no user auth)
+		api.Post("/payments/webhook/:provider", topUpHandler.Webhook)
+		if cfg.PaymentProvider == "fake" {
+			// Payment page of the fake provider (development)
+			api.Post("/payments/fake/orders/:reference", authMiddleware, topUpHandler.SimulatePayment)
+		}
+	}
+
+	// Admin Routes (Protected). Permissions are checked per route because
+	// moderators can work the report queue but not manage users.
+	admin := api.Group("/admin", authMiddleware)
+	manageUsers := utils.RequirePermission(utils.PermManageUsers)
+	admin.Get("/users", manageUsers, adminHandler.ListUsers)
+	admin.Get("/users/:id", manageUsers, adminHandler.GetUser)
+	admin.Post("/users/:id/ban", manageUsers, adminHandler.BanUser)
+	admin.Post("/users/:id/unban", manageUsers, adminHandler.UnbanUser)
+	admin.Post("/users/:id/verify", manageUsers, adminHandler.VerifyUser)
+	admin.Put("/users/:id/role", manageUsers, adminHandler.ChangeRole)
+	admin.Post("/users/:id/reset-points", manageUsers, adminHandler.ResetPoints)
+	admin.Get("/users/:id/points", manageUsers, pointsHandler.GetUserHistory)
+	admin.Get("/login-attempts", manageUsers, adminHandler.ListLoginAttempts)
+
+	// Moderation Queue (Protected, moderator or admin)
+	moderateReports := utils.RequirePermission(utils.PermModerateReports)
+	admin.Get("/reports", moderateReports, moderationHandler.ListReports)
+	admin.Get("/reports/:id", moderateReports, moderationHandler.GetReport)
+	admin.Post("/reports/:id/assign", moderateReports, moderationHandler.AssignReport)
+	admin.Put("/reports/:id/status", moderateReports, moderationHandler.UpdateReportStatus)
+	admin.Post("/reports/:id/resolve", moderateReports, moderationHandler.ResolveReport)
+	admin.Get("/disputes", moderateReports, moderationHandler.ListDisputes)
+	admin.Get("/disputes/:id", moderateReports, moderationHandler.GetDispute)
+	admin.Post("/disputes/:id/resolve", moderateReports, moderationHandler.ResolveDispute)
+
+	// WebSocket Ticket (Protected)
+	api.Post("/ws/ticket", authMiddleware, chatHandler.CreateTicket)
 
 	// Middleware for WebSocket Upgrade & Auth
 	app.Use("/ws", func(c *fiber.Ctx) error {
@@ -162,43 +385,39 @@ func main() {
 			return fiber.ErrUpgradeRequired
 		}
 
-		// 2. Validate Token from Query Param
-		tokenString := c.Query("token")
-		if tokenString == "" {
-			return fiber.ErrUnauthorized
-		}
-
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
+		// 2. Authenticate with a single-use ticket from POST /api/ws/ticket.
+		// Bearer tokens are not accepted in the query string because URLs end up in access logs.
+		var userID, sessionID uint
+		if ticket := c.Query("ticket"); ticket != "" {
+			var ok bool
+			userID, sessionID, ok = wsTickets.Redeem(ticket, c.IP())
+			if !ok {
+				return fiber.ErrUnauthorized
+			}
+		} else {
+			// 3. Fallback for clients that cannot call the ticket endpoint:
+			// Sec-WebSocket-Protocol: access_token, <JWT>
+			protocols := strings.Split(c.Get(fiber.HeaderSecWebSocketProtocol), ",")
+			if len(protocols) != 2 || strings.TrimSpace(protocols[0]) != "access_token" {
+				return fiber.ErrUnauthorized
+			}
 
-		if err != nil || !token.Valid {
-			return fiber.ErrUnauthorized
+			claims, err := utils.ValidateAccessToken(db, tokens, strings.TrimSpace(protocols[1]))
+			if err != nil {
+				return fiber.ErrUnauthorized
+			}
+			userID, sessionID = utils.ClaimUint(claims, "user_id"), utils.ClaimUint(claims, "sid")
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
+		// 4. Reject sessions that were revoked after the ticket was issued
+		if err := utils.CheckSession(db, sessionID, userID); err != nil {
 			return fiber.ErrUnauthorized
 		}
 
-		// Extract user_id and pass to locals using the exact key expected by ChatHandler
-		// Note: ChatHandler currently reads from Query("user_id"), we need to change that OR
-		// we just update ChatHandler to read from Locals if we were using middleware there.
-		// BUT: fiber/contrib/websocket creates a new *conn context. Locals MIGHT NOT persist easily strictly
-		// inside the websocket handler without some tricks.
-		// EASIER PATH: The ChatHandler parses query param "user_id".
-		// We should VALIDATE that the "user_id" in query param matches the Token.
-
-		userIDFromToken := uint(claims["user_id"].(float64))
-
-		// For simplicity/security, we will OVERRIDE the user_id query param with the one from token
-		// or just rely on the token validation.
-
-		// Let's pass the valid user ID in Locals, and update ChatHandler to check Locals OR Query.
-		// However, standard websocket handler in fiber doesn't easy share Locals to the conn wrapper?
-		// Actually it does: c.Locals key is copied to conn.Locals.
-
-		c.Locals("user_id", userIDFromToken)
+		// fiber/contrib/websocket copies c.Locals to the *websocket.Conn, so the
+		// chat handler can read the authenticated user from there.
+		c.Locals("user_id", userID)
+		c.Locals("session_id", sessionID)
 
 		return c.Next()
 	})
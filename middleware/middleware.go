@@ -39,8 +39,8 @@ func SetupMiddleware(app *fiber.App) {
 	// CORS middleware
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     "*",
-		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders:     "Origin,Content-Type,Accept,Authorization",
+		AllowMethods:     "GET,POST,PUT,PATCH,DELETE,OPTIONS",
+		AllowHeaders:     "Origin,Content-Type,Accept,Authorization,X-API-Key",
 		AllowCredentials: false,
 		ExposeHeaders:    "X-Request-ID",
 		MaxAge:           86400, // 24 hours
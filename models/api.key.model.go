@@ -0,0 +1,48 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// APIKey is a personal access key for scripts and integrations, sent in the X-API-Key header.
+// The key looks like mk_<prefix>_<secret>; the prefix identifies it and only the SHA-256
+// hash of the full key is stored.
+type APIKey struct {
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	UserID  uint   `gorm:"index;not null" json:"user_id"`
+	Name    string `gorm:"size:100;not null" json:"name"`
+	Prefix  string `gorm:"size:16;uniqueIndex;not null" json:"prefix"`
+	KeyHash string `gorm:"size:64;not null" json:"-"`
+	Scopes  string `gorm:"size:255;not null" json:"-"` // Dipisah spasi, mis. "products:write chat:read"
+
+	ExpiresAt  *time.Time `json:"expires_at"` // Kosong = tidak pernah kadaluarsa
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `gorm:"size:45" json:"last_used_ip"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ScopeList returns the scopes granted to the key
+func (k *APIKey) ScopeList() []string {
+	return strings.Fields(k.Scopes)
+}
+
+// HasScope reports whether the key grants the scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsActive reports whether the key is neither revoked nor expired
+func (k *APIKey) IsActive() bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || time.Now().Before(*k.ExpiresAt)
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// Block hides BlockerID and BlockedID from each other: no chats, no search
+// results, no listings and no presence updates in either direction
+type Block struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	BlockerID uint      `gorm:"not null;uniqueIndex:idx_blocker_blocked" json:"blocker_id"`
+	BlockedID uint      `gorm:"not null;uniqueIndex:idx_blocker_blocked;index" json:"blocked_id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Relasi
+	Blocked User `gorm:"foreignKey:BlockedID" json:"-"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// Follow means FollowerID follows FollowingID and sees their new products in the feed
+type Follow struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	FollowerID  uint      `gorm:"not null;uniqueIndex:idx_follower_following" json:"follower_id"`
+	FollowingID uint      `gorm:"not null;uniqueIndex:idx_follower_following;index" json:"following_id"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// Relasi
+	Follower  User `gorm:"foreignKey:FollowerID" json:"-"`
+	Following User `gorm:"foreignKey:FollowingID" json:"-"`
+}
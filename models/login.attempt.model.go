@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// LoginAttempt is an audit record of a login attempt. Failed attempts inside
+// the throttle window drive backoff and temporary lockout.
+type LoginAttempt struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	Email     string `gorm:"size:100;index:idx_login_attempt_email" json:"email"` // As typed, the account may not exist
+	UserID    *uint  `gorm:"index" json:"user_id"`
+	IPAddress string `gorm:"size:45;index:idx_login_attempt_ip" json:"ip_address"`
+	UserAgent string `gorm:"size:255" json:"user_agent"`
+
+	Success bool   `gorm:"default:false" json:"success"`
+	Reason  string `gorm:"size:50" json:"reason"` // unknown_email, bad_password, bad_2fa_code, ...
+
+	CreatedAt time.Time `gorm:"index:idx_login_attempt_email;index:idx_login_attempt_ip" json:"created_at"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+)
+
+// Meetup statuses. A confirmed meetup waits for its participants to report
+// what happened; the other statuses are final except disputed.
+const (
+	MeetupConfirmed = "confirmed"
+	MeetupCompleted = "completed"
+	MeetupNoShow    = "no_show"   // Someone did not show up; the others were refunded
+	MeetupDisputed  = "disputed"  // Reports disagree, waiting for a moderator
+	MeetupCancelled = "cancelled" // Settled by a moderator with a refund for everyone
+)
+
+// Outcomes a participant can report
+const (
+	OutcomeCompleted = "completed"
+	OutcomeNoShow    = "no_show"
+)
+
+// Meetup is recorded when every ready participant of a chat room agreed to meet
+// and paid the meetup cost
+type Meetup struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	ChatRoomID  uint      `gorm:"index;not null" json:"chat_room_id"`
+	PointsCost  int       `gorm:"not null" json:"points_cost"` // Dibayar oleh setiap peserta
+	ConfirmedAt time.Time `gorm:"not null" json:"confirmed_at"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// Hasil pertemuan
+	Status         string     `gorm:"size:20;not null;default:'confirmed';index" json:"status"`
+	DisputedAt     *time.Time `json:"disputed_at,omitempty"`
+	SettledAt      *time.Time `json:"settled_at,omitempty"`
+	ResolvedBy     *uint      `json:"resolved_by,omitempty"` // Moderator yang memutus sengketa
+	ResolutionNote string     `gorm:"type:text" json:"resolution_note,omitempty"`
+
+	// Relasi
+	Participants []MeetupParticipant `json:"participants"`
+}
+
+// MeetupParticipant is a user who confirmed the meetup
+type MeetupParticipant struct {
+	ID       uint `gorm:"primaryKey" json:"id"`
+	MeetupID uint `gorm:"not null;uniqueIndex:idx_meetup_participant" json:"meetup_id"`
+	UserID   uint `gorm:"not null;uniqueIndex:idx_meetup_participant;index" json:"user_id"`
+
+	// Laporan hasil dari peserta ini
+	Outcome      string     `gorm:"size:20" json:"outcome,omitempty"`
+	AbsentUserID *uint      `json:"absent_user_id,omitempty"` // Untuk no_show: siapa yang tidak datang menurut peserta ini
+	ReportedAt   *time.Time `json:"reported_at,omitempty"`
+	NoShow       bool       `gorm:"default:false" json:"no_show"` // Keputusan akhir: peserta ini tidak datang
+
+	// Relasi
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
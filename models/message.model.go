@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"gorm.io/gorm"
@@ -22,6 +23,22 @@ type Message struct {
 	CreatedAt time.Time      `json:"created_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
 
-	// Relasi
-	Sender User `gorm:"foreignKey:SenderID" json:"sender"`
+	// Relasi (di JSON hanya bagian publik, lihat MarshalJSON)
+	Sender User `gorm:"foreignKey:SenderID" json:"-"`
+}
+
+// MarshalJSON sends only the public part of the sender, since messages go to
+// the chat partner and the full user row holds the phone number and location
+func (m Message) MarshalJSON() ([]byte, error) {
+	type message Message // Without this method
+	return json.Marshal(struct {
+		message
+		Sender UserSummary `json:"sender"`
+	}{message(m), m.Sender.Summary()})
+}
+
+// PublicSender is a Preload("Sender") condition that loads only the public
+// columns, so the sender's phone number and exact location are never read
+func PublicSender(db *gorm.DB) *gorm.DB {
+	return db.Select(UserSummaryColumns)
 }
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// OAuthState tracks one pending OpenID Connect authorization request.
+// The state value itself is only stored as a SHA-256 hash; nonce and PKCE
+// verifier never leave the server.
+type OAuthState struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	StateHash string `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	Provider  string `gorm:"size:50;not null" json:"provider"`
+
+	Nonce        string `gorm:"size:64;not null" json:"-"`
+	CodeVerifier string `gorm:"size:128;not null" json:"-"`
+
+	UserID     *uint  `json:"user_id"` // Diisi jika ini permintaan link akun, kosong untuk login
+	DeviceName string `gorm:"size:100" json:"device_name"`
+
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// PasswordReset is a single-use token sent by email to reset a forgotten password.
+// Only the SHA-256 hash of the token is stored.
+type PasswordReset struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	UserID    uint   `gorm:"index;not null" json:"user_id"`
+	TokenHash string `gorm:"size:64;uniqueIndex;not null" json:"-"`
+
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	IPAddress string     `gorm:"size:45" json:"ip_address"` // Who requested it
+
+	CreatedAt time.Time `json:"created_at"`
+}
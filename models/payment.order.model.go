@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+)
+
+// Payment order statuses. Points are credited only when an order becomes paid,
+// and taken back when a paid order is refunded.
+const (
+	PaymentOrderPending  = "pending"
+	PaymentOrderPaid     = "paid"
+	PaymentOrderFailed   = "failed"
+	PaymentOrderExpired  = "expired"
+	PaymentOrderRefunded = "refunded"
+)
+
+// PaymentOrder is a purchase of a point package
+type PaymentOrder struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	Reference string `gorm:"size:40;uniqueIndex;not null" json:"reference"` // Dikirim ke provider pembayaran
+	UserID    uint   `gorm:"index;not null" json:"user_id"`
+	PackageID uint   `gorm:"not null" json:"package_id"`
+
+	// Salinan paket saat checkout (paket bisa berubah setelahnya)
+	Points   int    `gorm:"not null" json:"points"`
+	Amount   int64  `gorm:"not null" json:"amount"`
+	Currency string `gorm:"size:3;not null" json:"currency"`
+
+	Provider          string `gorm:"size:30;not null" json:"provider"`
+	ProviderReference string `gorm:"size:100;index" json:"provider_reference,omitempty"`
+	PaymentURL        string `gorm:"size:500" json:"payment_url,omitempty"`
+
+	Status          string     `gorm:"size:20;not null;default:'pending';index" json:"status"`
+	ExpiresAt       time.Time  `gorm:"index" json:"expires_at"`
+	PaidAt          *time.Time `json:"paid_at,omitempty"`
+	RefundedAt      *time.Time `json:"refunded_at,omitempty"`
+	RefundShortfall int        `gorm:"default:0" json:"refund_shortfall,omitempty"` // Poin yang sudah terpakai saat refund
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PaymentEvent is a webhook received from a payment provider. Each event is
+// processed once, however often the provider delivers it.
+type PaymentEvent struct {
+	ID       uint   `gorm:"primaryKey"`
+	Provider string `gorm:"size:30;not null;uniqueIndex:idx_payment_event"`
+	EventID  string `gorm:"size:100;not null;uniqueIndex:idx_payment_event"`
+	OrderID  *uint  `gorm:"index"`
+	Status   string `gorm:"size:20"`
+	Payload  string `gorm:"type:text"`
+
+	CreatedAt time.Time
+}
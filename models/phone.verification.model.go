@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// PhoneVerification is a one-time code sent by SMS to prove ownership of a phone number.
+// The number only becomes User.Phone once the code is confirmed.
+// Only the SHA-256 hash of the code is stored.
+type PhoneVerification struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	UserID   uint   `gorm:"index;not null" json:"user_id"`
+	Phone    string `gorm:"size:20;not null" json:"phone"`
+	CodeHash string `gorm:"size:64;not null" json:"-"`
+
+	Attempts  int        `gorm:"default:0" json:"attempts"` // Percobaan kode yang salah
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"` // Diisi saat berhasil diverifikasi atau diganti kode baru
+
+	CreatedAt time.Time `json:"created_at"`
+}
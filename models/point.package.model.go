@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// PointPackage is a bundle of points that can be bought
+type PointPackage struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	Code     string `gorm:"size:50;uniqueIndex;not null" json:"code"`
+	Name     string `gorm:"size:100;not null" json:"name"`
+	Points   int    `gorm:"not null" json:"points"`
+	Price    int64  `gorm:"not null" json:"price"` // Dalam satuan terkecil mata uang (IDR tidak punya sen)
+	Currency string `gorm:"size:3;not null;default:'IDR'" json:"currency"`
+	Active   bool   `gorm:"default:true" json:"active"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// PointTransaction is an entry in the append-only points ledger. User.Points is
+// always the BalanceAfter of the user's latest entry.
+type PointTransaction struct {
+	ID           uint   `gorm:"primaryKey" json:"id"`
+	UserID       uint   `gorm:"not null;index:idx_point_tx_user" json:"user_id"`
+	Delta        int    `gorm:"not null" json:"delta"` // Positif = masuk, negatif = keluar
+	BalanceAfter int    `gorm:"not null" json:"balance_after"`
+	Reason       string `gorm:"size:30;not null" json:"reason"`
+	Note         string `gorm:"size:255" json:"note,omitempty"`
+
+	// Referensi ke penyebab perubahan
+	ChatRoomID *uint `gorm:"index" json:"chat_room_id,omitempty"`
+	MeetupID   *uint `gorm:"index" json:"meetup_id,omitempty"`
+	ActorID    *uint `json:"actor_id,omitempty"` // Admin yang melakukan perubahan manual
+
+	// Mencegah perubahan yang sama tercatat dua kali (mis. request yang diulang)
+	IdempotencyKey *string `gorm:"size:100;uniqueIndex" json:"-"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -18,6 +18,22 @@ type Product struct {
 	Images      []string `gorm:"serializer:json" json:"images"`
 	Status      string   `gorm:"default:'available';size:20" json:"status"` // available, sold
 
+	// Disembunyikan dari listing (laporan melewati batas, atau oleh moderator)
+	HiddenAt     *time.Time `gorm:"index" json:"hidden_at,omitempty"`
+	HiddenReason string     `gorm:"size:20" json:"hidden_reason,omitempty"` // reports, moderator
+
+	// Lokasi Pengambilan (kosong = pakai lokasi penjual)
+	Latitude       *float64 `gorm:"index:idx_product_location" json:"latitude"`
+	Longitude      *float64 `gorm:"index:idx_product_location" json:"longitude"`
+	AreaName       string   `gorm:"size:100" json:"area_name"`
+	PrivacyRadiusM int      `gorm:"default:0" json:"privacy_radius_m"` // Koordinat disamarkan sejauh ini untuk selain pemilik
+	City           string   `gorm:"size:100;index" json:"city"`        // Dari lokasi pengambilan/penjual, untuk filter
+	Province       string   `gorm:"size:100;index" json:"province"`    // Dari lokasi pengambilan/penjual, untuk filter
+
+	// Diisi saat response, tidak disimpan
+	LocationSource      string `gorm:"-" json:"location_source,omitempty"` // pickup, seller
+	LocationApproximate bool   `gorm:"-" json:"location_approximate"`
+
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
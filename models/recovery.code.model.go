@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// RecoveryCode is a one-time backup code for logging in when the authenticator app is unavailable.
+// Only the SHA-256 hash of the code is stored.
+type RecoveryCode struct {
+	ID       uint       `gorm:"primaryKey" json:"id"`
+	UserID   uint       `gorm:"index;not null" json:"user_id"`
+	CodeHash string     `gorm:"size:64;not null" json:"-"`
+	UsedAt   *time.Time `json:"used_at"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
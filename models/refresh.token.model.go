@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken is a single-use token in a session's rotation chain.
+// Only the SHA-256 hash of the token is stored.
+type RefreshToken struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	SessionID uint   `gorm:"index;not null" json:"session_id"`
+	TokenHash string `gorm:"size:64;uniqueIndex;not null" json:"-"`
+
+	// UsedAt is set when the token has been exchanged for a new one.
+	// Presenting a used token again means it was leaked (reuse detection).
+	UsedAt    *time.Time `json:"used_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+
+	CreatedAt time.Time `json:"created_at"`
+
+	// Relasi
+	Session Session `gorm:"foreignKey:SessionID" json:"-"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+// Report targets
+const (
+	ReportTargetUser    = "user"
+	ReportTargetProduct = "product"
+	ReportTargetMessage = "message"
+)
+
+// Report statuses. Open and in_review reports are waiting for a moderator.
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusInReview  = "in_review"
+	ReportStatusResolved  = "resolved"
+	ReportStatusDismissed = "dismissed"
+)
+
+// Report flags a user, product or chat message for the moderation queue.
+// A user can report the same target only once.
+type Report struct {
+	ID             uint   `gorm:"primaryKey" json:"id"`
+	ReporterID     uint   `gorm:"not null;uniqueIndex:idx_report_reporter_target" json:"reporter_id"`
+	TargetType     string `gorm:"size:20;not null;uniqueIndex:idx_report_reporter_target;index:idx_report_target" json:"target_type"`
+	TargetID       uint   `gorm:"not null;uniqueIndex:idx_report_reporter_target;index:idx_report_target" json:"target_id"`
+	ReportedUserID uint   `gorm:"index;not null" json:"reported_user_id"` // Pemilik produk / pengirim pesan / user yang dilaporkan
+
+	Reason  string `gorm:"size:30;not null" json:"reason"`
+	Details string `gorm:"type:text" json:"details"`
+	// Salinan isi pesan saat dilaporkan (pesan dihapus setelah dibaca)
+	MessageSnapshot string `gorm:"type:text" json:"message_snapshot,omitempty"`
+
+	// Moderasi
+	Status         string     `gorm:"size:20;not null;default:'open';index" json:"status"`
+	AssigneeID     *uint      `gorm:"index" json:"assignee_id"`
+	Resolution     string     `gorm:"size:20" json:"resolution,omitempty"` // dismiss, hide_product, warn, suspend
+	ResolutionNote string     `gorm:"type:text" json:"resolution_note,omitempty"`
+	ResolvedBy     *uint      `json:"resolved_by,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IsClosed reports whether a moderator already decided on the report
+func (r *Report) IsClosed() bool {
+	return r.Status == ReportStatusResolved || r.Status == ReportStatusDismissed
+}
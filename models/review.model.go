@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// Review is a rating one participant of a confirmed meetup gives another.
+// It stays hidden until the other side reviewed back or the review window closed,
+// so neither review can be written in response to the other.
+type Review struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	MeetupID   uint   `gorm:"not null;uniqueIndex:idx_review_meetup_pair" json:"meetup_id"`
+	ReviewerID uint   `gorm:"not null;uniqueIndex:idx_review_meetup_pair;index" json:"reviewer_id"`
+	RevieweeID uint   `gorm:"not null;uniqueIndex:idx_review_meetup_pair;index:idx_review_reviewee" json:"reviewee_id"`
+	Rating     int    `gorm:"not null" json:"rating"` // 1-5
+	Comment    string `gorm:"type:text" json:"comment"`
+
+	// Ditampilkan mulai waktu ini: saat kedua pihak sudah mengulas, atau saat masa ulasan berakhir
+	VisibleAt time.Time `gorm:"not null;index:idx_review_reviewee" json:"visible_at"`
+
+	CreatedAt time.Time `json:"created_at"`
+
+	// Relasi
+	Reviewer *User `gorm:"foreignKey:ReviewerID" json:"reviewer,omitempty"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// Session represents one logged-in device. All refresh tokens issued from the
+// same login belong to the same session (token family).
+type Session struct {
+	ID     uint `gorm:"primaryKey" json:"id"`
+	UserID uint `gorm:"index;not null" json:"user_id"`
+
+	// Informasi Device
+	DeviceName string `gorm:"size:100" json:"device_name"`
+	IPAddress  string `gorm:"size:45" json:"ip_address"`
+	UserAgent  string `gorm:"size:255" json:"user_agent"`
+
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+
+	// Revocation
+	RevokedAt     *time.Time `gorm:"index" json:"revoked_at"`
+	RevokedReason string     `gorm:"size:50" json:"revoked_reason,omitempty"` // logout, refresh_token_reuse, ...
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Relasi
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// IsActive reports whether the session can still be used
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// SigningKey is a JWT signing key pair. Keys are stored in the database so
+// every server instance signs and verifies with the same set.
+type SigningKey struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	KID        string `gorm:"size:64;uniqueIndex;not null" json:"kid"`
+	Algorithm  string `gorm:"size:16;not null" json:"algorithm"` // RS256, EdDSA
+	PrivateKey string `gorm:"type:text;not null" json:"-"`       // PKCS#8 PEM
+	PublicKey  string `gorm:"type:text;not null" json:"-"`       // PKIX PEM
+
+	// RetiresAt: stop signing new tokens with this key.
+	// ExpiresAt: stop accepting tokens signed with it and drop it from the JWKS.
+	RetiresAt time.Time `gorm:"index" json:"retires_at"`
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// Upload is a file stored under ./uploads. Only the uploader's own files are
+// ever deleted on their behalf.
+type Upload struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"index;not null" json:"user_id"`
+	URL       string    `gorm:"size:255;uniqueIndex;not null" json:"url"` // Path publik, mis. /uploads/avatars/123.png
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// UserIdentity links a user to an account at an external OpenID Connect provider.
+// A provider account (Provider + Subject) can belong to only one user.
+type UserIdentity struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	UserID   uint   `gorm:"index;not null" json:"user_id"`
+	Provider string `gorm:"size:50;not null;uniqueIndex:idx_provider_subject" json:"provider"`
+	Subject  string `gorm:"size:255;not null;uniqueIndex:idx_provider_subject" json:"-"` // "sub" claim dari ID token
+	Email    string `gorm:"size:100" json:"email"`                                       // Email di provider saat terakhir login
+
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
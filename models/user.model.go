@@ -23,12 +23,48 @@ type User struct {
 	Role       string `gorm:"default:'user';size:20" json:"role"` // user, admin, moderator
 	IsVerified bool   `gorm:"default:false" json:"is_verified"`
 	IsOnline   bool   `gorm:"default:false" json:"is_online"`
-	Points     int    `gorm:"default:10" json:"points"`
+	Points     int    `gorm:"default:0" json:"points"` // Saldo, hanya diubah lewat internal/points
+
+	// Verifikasi Nomor HP
+	PhoneVerified        bool       `gorm:"default:false" json:"phone_verified"`
+	PhoneVerifiedAt      *time.Time `json:"phone_verified_at"`
+	RequireVerifiedPhone bool       `gorm:"default:false" json:"require_verified_phone"` // Pembeli wajib punya nomor HP terverifikasi untuk memulai chat
+
+	// Verifikasi Email
+	EmailVerifiedAt    *time.Time `json:"email_verified_at"`
+	VerificationSentAt *time.Time `json:"-"` // Untuk throttling kirim ulang
+
+	// Two-Factor Authentication (TOTP)
+	TOTPEnabled  bool   `gorm:"default:false" json:"two_factor_enabled"`
+	TOTPSecret   string `gorm:"size:64" json:"-"` // Diisi saat setup, aktif setelah dikonfirmasi
+	TOTPLastStep int64  `json:"-"`                // Step terakhir yang dipakai, mencegah replay kode
+
+	// Ban / Suspend (diatur oleh admin)
+	BannedAt    *time.Time `json:"banned_at,omitempty"`
+	BannedUntil *time.Time `json:"banned_until,omitempty"` // Kosong = permanen
+	BanReason   string     `gorm:"size:255" json:"ban_reason,omitempty"`
+	BannedBy    *uint      `json:"banned_by,omitempty"`
+
+	// Peringatan dari moderator (hasil laporan)
+	WarningCount int        `gorm:"default:0" json:"warning_count"`
+	LastWarnedAt *time.Time `json:"last_warned_at,omitempty"`
+
+	// Janji temu yang tidak didatangi (hasil laporan peserta atau keputusan moderator)
+	NoShowCount int `gorm:"default:0" json:"no_show_count"`
+
+	// Penghapusan Akun (dengan masa tenggang)
+	DeletionRequestedAt *time.Time `json:"deletion_requested_at,omitempty"`
+	DeletionScheduledAt *time.Time `gorm:"index" json:"deletion_scheduled_at,omitempty"` // Data dianonimkan setelah waktu ini
+	AnonymizedAt        *time.Time `json:"-"`
 
 	// Lokasi (Indexed untuk performa pencarian geospasial)
 	Latitude  float64 `gorm:"index:idx_location" json:"latitude"`
 	Longitude float64 `gorm:"index:idx_location" json:"longitude"`
-	Address   string  `gorm:"type:text" json:"address"` // Alamat lengkap opsional
+	Address   string  `gorm:"type:text" json:"address"`       // Alamat lengkap opsional
+	City      string  `gorm:"size:100;index" json:"city"`     // Diisi otomatis dari koordinat (gazetteer)
+	Province  string  `gorm:"size:100;index" json:"province"` // Diisi otomatis dari koordinat (gazetteer)
+
+	HideExactLocation bool `gorm:"default:false" json:"hide_exact_location"` // Hanya tampilkan area perkiraan ke pengguna lain
 
 	// System Timestamps
 	CreatedAt time.Time `json:"created_at"`
@@ -37,3 +73,28 @@ type User struct {
 	// Soft Delete yang Benar
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
 }
+
+// IsBanned reports whether the user is currently banned or suspended
+func (u *User) IsBanned() bool {
+	if u.BannedAt == nil {
+		return false
+	}
+	return u.BannedUntil == nil || time.Now().Before(*u.BannedUntil)
+}
+
+// UserSummary is the public part of a user shown to other users, e.g. next to
+// chat messages, reviews and meetups
+type UserSummary struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+	FullName string `json:"full_name"`
+	ImageURL string `json:"image_url"`
+}
+
+// UserSummaryColumns are the only columns needed for a UserSummary
+const UserSummaryColumns = "id, username, full_name, image_url"
+
+// Summary returns the public part of the user
+func (u *User) Summary() UserSummary {
+	return UserSummary{ID: u.ID, Username: u.Username, FullName: u.FullName, ImageURL: u.ImageURL}
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"errors"
+	"meetup_backend/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// HeaderAPIKey carries a personal API key instead of a bearer token
+const HeaderAPIKey = "X-API-Key"
+
+// API key scopes, checked by AuthMiddleware for requests authenticated with an API key
+const (
+	ScopeProductsRead  = "products:read"  // List own products
+	ScopeProductsWrite = "products:write" // Create, update and delete own products, upload images
+	ScopeChatRead      = "chat:read"      // List rooms, read messages and room status
+	ScopeChatWrite     = "chat:write"     // Start and delete chats
+)
+
+// APIKeyScopes lists every scope a key can be granted
+var APIKeyScopes = []string{ScopeProductsRead, ScopeProductsWrite, ScopeChatRead, ScopeChatWrite}
+
+const apiKeyPrefix = "mk_"
+
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// IsValidScope reports whether scope is one of APIKeyScopes
+func IsValidScope(scope string) bool {
+	for _, s := range APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateAPIKey returns a new key (shown to the user once) and its public prefix
+func GenerateAPIKey() (key string, prefix string, err error) {
+	prefix, err = GenerateRandomToken(4)
+	if err != nil {
+		return "", "", err
+	}
+	secret, err := GenerateRandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	return apiKeyPrefix + prefix + "_" + secret, prefix, nil
+}
+
+// AuthenticateAPIKey finds the active key matching the presented value and records its use
+func AuthenticateAPIKey(db *gorm.DB, presented string, ip string) (*models.APIKey, error) {
+	rest, ok := strings.CutPrefix(presented, apiKeyPrefix)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+	prefix, _, ok := strings.Cut(rest, "_")
+	if !ok || prefix == "" {
+		return nil, ErrInvalidAPIKey
+	}
+
+	var key models.APIKey
+	if err := db.Where("prefix = ?", prefix).First(&key).Error; err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+	if subtle.ConstantTimeCompare([]byte(HashToken(presented)), []byte(key.KeyHash)) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+	if !key.IsActive() {
+		return nil, ErrInvalidAPIKey
+	}
+
+	// Record usage at most once a minute to avoid a write on every request
+	now := time.Now()
+	db.Model(&models.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", key.ID, now.Add(-time.Minute)).
+		Updates(map[string]interface{}{"last_used_at": now, "last_used_ip": ip})
+
+	return &key, nil
+}
@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"meetup_backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// BanUser bans (until nil) or suspends the user and logs out all their devices.
+// Callers should also drop live websocket connections with Hub.DisconnectUser.
+func BanUser(db *gorm.DB, user *models.User, bannedBy uint, reason string, until *time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"banned_at":    time.Now(),
+			"banned_until": until,
+			"ban_reason":   reason,
+			"banned_by":    bannedBy,
+		}).Error; err != nil {
+			return err
+		}
+		return RevokeUserSessions(tx, user.ID, 0, "banned")
+	})
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"meetup_backend/models"
+
+	"gorm.io/gorm"
+)
+
+// BlockedWith returns the users that userID blocked or was blocked by
+func BlockedWith(db *gorm.DB, userID uint) map[uint]bool {
+	var blocks []models.Block
+	if err := db.Where("blocker_id = ? OR blocked_id = ?", userID, userID).Find(&blocks).Error; err != nil {
+		return map[uint]bool{}
+	}
+
+	blocked := make(map[uint]bool, len(blocks))
+	for _, b := range blocks {
+		if b.BlockerID == userID {
+			blocked[b.BlockedID] = true
+		} else {
+			blocked[b.BlockerID] = true
+		}
+	}
+	return blocked
+}
+
+// IsBlockedBetween reports whether either user blocked the other
+func IsBlockedBetween(db *gorm.DB, a, b uint) bool {
+	var count int64
+	db.Model(&models.Block{}).
+		Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)", a, b, b, a).
+		Count(&count)
+	return count > 0
+}
+
+// ExcludeBlocked is a query scope that drops rows whose column refers to a user
+// in a block with userID. It does nothing for anonymous requests (userID 0).
+func ExcludeBlocked(userID uint, column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if userID == 0 {
+			return db
+		}
+		blocked := db.Session(&gorm.Session{NewDB: true}).Model(&models.Block{}).Select("blocked_id").Where("blocker_id = ?", userID)
+		blockedBy := db.Session(&gorm.Session{NewDB: true}).Model(&models.Block{}).Select("blocker_id").Where("blocked_id = ?", userID)
+		return db.Where(column+" NOT IN (?) AND "+column+" NOT IN (?)", blocked, blockedBy)
+	}
+}
@@ -0,0 +1,66 @@
+package utils
+
+import "math"
+
+const earthRadiusKm = 6371.0
+
+const metersPerDegree = 111320.0
+
+// HiddenLocationRadiusM is the minimum fuzzing applied to locations of sellers
+// who chose to hide their exact location
+const HiddenLocationRadiusM = 1000
+
+// HaversineKm returns the great-circle distance between two points in kilometres
+func HaversineKm(lat1, lng1, lat2, lng2 float64) float64 {
+	dLat := toRadians(lat2 - lat1)
+	dLng := toRadians(lng2 - lng1)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// BoundingBox returns the smallest lat/lng rectangle containing every point within
+// radiusKm of the centre. It is used as an indexable prefilter before the exact
+// haversine check. Near the poles or the antimeridian the longitude range is widened
+// to the whole globe instead of wrapping around.
+func BoundingBox(lat, lng, radiusKm float64) (minLat, maxLat, minLng, maxLng float64) {
+	dLat := radiusKm / earthRadiusKm * 180 / math.Pi
+	minLat, maxLat = lat-dLat, lat+dLat
+
+	if minLat <= -90 || maxLat >= 90 {
+		return math.Max(minLat, -90), math.Min(maxLat, 90), -180, 180
+	}
+
+	dLng := math.Asin(math.Sin(radiusKm/earthRadiusKm)/math.Cos(toRadians(lat))) * 180 / math.Pi
+	minLng, maxLng = lng-dLng, lng+dLng
+	if minLng < -180 || maxLng > 180 {
+		return minLat, maxLat, -180, 180
+	}
+	return minLat, maxLat, minLng, maxLng
+}
+
+// ApproximateCoordinates snaps a location to a grid of the given precision in degrees
+func ApproximateCoordinates(lat, lng, precision float64) (float64, float64) {
+	scale := math.Round(1 / precision) // dividing by the scale keeps results like -6.21 exact
+	return math.Round(lat*scale) / scale, math.Round(lng*scale) / scale
+}
+
+// FuzzCoordinates snaps a location to a grid cell about radiusM wide. Snapping
+// (instead of random noise) gives the same answer on every request, so averaging
+// many responses does not reveal the exact point.
+func FuzzCoordinates(lat, lng float64, radiusM int) (float64, float64) {
+	if radiusM <= 0 {
+		return lat, lng
+	}
+	return ApproximateCoordinates(lat, lng, float64(radiusM)/metersPerDegree)
+}
+
+// HasLocation reports whether coordinates were ever set (0,0 is the zero value)
+func HasLocation(lat, lng float64) bool {
+	return lat != 0 || lng != 0
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
@@ -1,76 +1,170 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
-	"os"
-	"time"
+	"meetup_backend/internal/token"
+	"meetup_backend/models"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
-func generateJWTSecret(userId uint) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": userId,
+var (
+	ErrSessionRevoked  = errors.New("session has been revoked")
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// ClaimUint reads a numeric claim (JSON numbers are decoded as float64)
+func ClaimUint(claims jwt.MapClaims, key string) uint {
+	if v, ok := claims[key].(float64); ok {
+		return uint(v)
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
-	return tokenString, err
+	return 0
 }
 
-func AuthMiddleware(c *fiber.Ctx) error {
-	authHeader := c.Get("Authorization")
-	if authHeader == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{ // Fixed: StatusBadRequest -> StatusUnauthorized
-			"error": "No Token Provided",
-		})
+// ValidateAccessToken parses the token and makes sure the session it belongs to is still active
+func ValidateAccessToken(db *gorm.DB, tokens *token.Service, tokenString string) (jwt.MapClaims, error) {
+	claims, err := tokens.ParseAccessToken(tokenString)
+	if err != nil {
+		return nil, err
 	}
 
-	var tokenString string
-	fmt.Sscanf(authHeader, "Bearer %s", &tokenString)
+	sessionID := ClaimUint(claims, "sid")
+	if sessionID == 0 || ClaimUint(claims, "user_id") == 0 {
+		return nil, token.ErrInvalidToken
+	}
 
-	if tokenString == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{ // Fixed: StatusBadGateway -> StatusUnauthorized
-			"error": "Token format is invalid",
-		})
+	if err := CheckSession(db, sessionID, ClaimUint(claims, "user_id")); err != nil {
+		return nil, err
 	}
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
+	return claims, nil
+}
 
-	if err != nil || !token.Valid {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{ // Fixed: StatusBadRequest -> StatusUnauthorized
-			"error": "Token is invalid",
-		})
-	}
+// AuthMiddleware validates the bearer token and stores user_id, role and session_id in Locals.
+//
+// When scopes are given, a personal API key holding all of them is accepted in the
+// X-API-Key header instead; session_id is then 0 and api_key_id is set. Without scopes
+// API keys are refused, so they can never reach account or admin endpoints.
+func AuthMiddleware(db *gorm.DB, tokens *token.Service, scopes ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var userID, sessionID uint
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok || !token.Valid {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{ // Fixed: StatusBadRequest -> StatusUnauthorized
-			"error": "Invalid token claims",
-		})
-	}
+		if presented := c.Get(HeaderAPIKey); presented != "" {
+			if len(scopes) == 0 {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error": "API keys cannot be used for this endpoint",
+				})
+			}
+
+			key, err := AuthenticateAPIKey(db, presented, c.IP())
+			if err != nil {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"error": "API key is invalid, expired or revoked",
+				})
+			}
+			for _, scope := range scopes {
+				if !key.HasScope(scope) {
+					return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+						"error": "API key is missing the required scope",
+						"scope": scope,
+					})
+				}
+			}
+
+			userID = key.UserID
+			c.Locals("api_key_id", key.ID)
+		} else {
+			claims, errorBody := bearerClaims(c, db, tokens)
+			if errorBody != nil {
+				return c.Status(fiber.StatusUnauthorized).JSON(errorBody)
+			}
+			userID, sessionID = ClaimUint(claims, "user_id"), ClaimUint(claims, "sid")
+		}
 
-	// Check token expiration
-	if exp, ok := claims["exp"].(float64); ok {
-		if time.Now().Unix() > int64(exp) {
+		// Load the role from the database instead of trusting the token claim,
+		// so a demotion takes effect immediately
+		var user models.User
+		if err := db.Select("id, role, banned_at, banned_until, ban_reason").First(&user, userID).Error; err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Token has expired",
+				"error": "User not found",
 			})
 		}
+
+		if user.IsBanned() {
+			return c.Status(fiber.StatusForbidden).JSON(BannedResponse(&user))
+		}
+
+		c.Locals("user_id", user.ID)
+		c.Locals("session_id", sessionID)
+		c.Locals("role", user.Role)
+
+		return c.Next()
 	}
+}
+
+// OptionalAuth identifies the user on public endpoints when a valid bearer token is
+// sent, so responses can be personalised. Requests without one (or with an invalid
+// one) continue anonymously with no user_id in Locals.
+func OptionalAuth(db *gorm.DB, tokens *token.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Get("Authorization") == "" {
+			return c.Next()
+		}
 
-	// Convert user_id to uint to avoid type assertion issues later
-	if userIDFloat, ok := claims["user_id"].(float64); ok {
-		c.Locals("user_id", uint(userIDFloat))
-	} else {
-		c.Locals("user_id", claims["user_id"])
+		claims, errorBody := bearerClaims(c, db, tokens)
+		if errorBody != nil {
+			return c.Next()
+		}
+
+		var user models.User
+		if err := db.Select("id, role, banned_at, banned_until").First(&user, ClaimUint(claims, "user_id")).Error; err != nil || user.IsBanned() {
+			return c.Next()
+		}
+
+		c.Locals("user_id", user.ID)
+		c.Locals("session_id", ClaimUint(claims, "sid"))
+		c.Locals("role", user.Role)
+		return c.Next()
 	}
-	c.Locals("role", claims["role"])
+}
 
-	return c.Next()
+// bearerClaims validates the Authorization header, returning the error body on failure
+func bearerClaims(c *fiber.Ctx, db *gorm.DB, tokens *token.Service) (jwt.MapClaims, fiber.Map) {
+	authHeader := c.Get("Authorization")
+	if authHeader == "" {
+		return nil, fiber.Map{"error": "No Token Provided"}
+	}
+
+	var tokenString string
+	fmt.Sscanf(authHeader, "Bearer %s", &tokenString)
+
+	if tokenString == "" {
+		return nil, fiber.Map{"error": "Token format is invalid"}
+	}
+
+	claims, err := ValidateAccessToken(db, tokens, tokenString)
+	if err != nil {
+		switch {
+		case errors.Is(err, token.ErrTokenExpired):
+			return nil, fiber.Map{"error": "Token has expired"}
+		case errors.Is(err, ErrSessionRevoked), errors.Is(err, ErrSessionNotFound):
+			return nil, fiber.Map{"error": "Session has been revoked"}
+		}
+		return nil, fiber.Map{"error": "Token is invalid"}
+	}
+
+	return claims, nil
+}
+
+// BannedResponse is the error body returned to banned users
+func BannedResponse(user *models.User) fiber.Map {
+	return fiber.Map{
+		"error":        "Your account has been suspended",
+		"code":         "account_banned",
+		"reason":       user.BanReason,
+		"banned_until": user.BannedUntil,
+	}
 }
@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"log"
+	"meetup_backend/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LoginThrottle slows down password guessing. Failed attempts are counted per
+// account (email) and per IP inside a sliding window. After FreeAttempts failures
+// every further attempt has to wait BaseDelay * 2^n (capped at MaxDelay), and at
+// the max attempts the key is locked for LockoutDuration. IPs get more free
+// attempts than accounts since many users can share one address.
+type LoginThrottle struct {
+	DB *gorm.DB
+
+	Window             time.Duration
+	FreeAttempts       int
+	AccountMaxAttempts int
+	IPFreeAttempts     int
+	IPMaxAttempts      int
+	BaseDelay          time.Duration
+	MaxDelay           time.Duration
+	LockoutDuration    time.Duration
+}
+
+// LoginAttemptReservation is a login attempt recorded before the credentials are
+// checked. Until it is resolved it counts as a failure, so parallel guesses are
+// throttled against each other instead of all passing the check at once.
+type LoginAttemptReservation struct {
+	throttle *LoginThrottle
+	attempt  models.LoginAttempt
+}
+
+// Reserve records a pending attempt and returns how long the caller has to wait
+// before trying again (0 = allowed) and whether the wait is a full lockout. Only
+// attempts recorded before this one are counted, so of several parallel requests
+// no more get through than the limits allow. A throttled attempt is not recorded.
+func (t *LoginThrottle) Reserve(email, ip, userAgent string) (*LoginAttemptReservation, time.Duration, bool) {
+	r := &LoginAttemptReservation{throttle: t, attempt: models.LoginAttempt{
+		Email:     normalizeEmail(email),
+		IPAddress: ip,
+		UserAgent: truncate(userAgent, 255),
+		Success:   false,
+		Reason:    "pending",
+	}}
+	if err := t.DB.Create(&r.attempt).Error; err != nil {
+		log.Printf("Failed to reserve login attempt: %v", err)
+		wait, locked := t.check(t.DB, r.attempt.Email, ip)
+		return r, wait, locked
+	}
+
+	wait, locked := t.check(t.DB.Where("id < ?", r.attempt.ID), r.attempt.Email, ip)
+	if wait > 0 {
+		r.Cancel()
+	}
+	return r, wait, locked
+}
+
+// check computes the wait from the attempts matched by base
+func (t *LoginThrottle) check(base *gorm.DB, email, ip string) (time.Duration, bool) {
+	since := time.Now().Add(-t.Window)
+
+	// A successful login resets the account counter, but not the IP counter,
+	// otherwise an attacker could reset it by logging into their own account.
+	var lastSuccess models.LoginAttempt
+	accountSince := since
+	if err := base.Session(&gorm.Session{}).Where("email = ? AND success = ? AND created_at > ?", email, true, since).
+		Order("created_at desc").First(&lastSuccess).Error; err == nil {
+		accountSince = lastSuccess.CreatedAt
+	}
+
+	accountWait, accountLocked := t.wait(base.Session(&gorm.Session{}).Where("email = ?", email), accountSince, t.FreeAttempts, t.AccountMaxAttempts)
+	ipWait, ipLocked := t.wait(base.Session(&gorm.Session{}).Where("ip_address = ?", ip), since, t.IPFreeAttempts, t.IPMaxAttempts)
+
+	if ipWait > accountWait {
+		return ipWait, ipLocked
+	}
+	return accountWait, accountLocked
+}
+
+// wait computes the backoff for the failed attempts matched by scope
+func (t *LoginThrottle) wait(scope *gorm.DB, since time.Time, freeAttempts, maxAttempts int) (time.Duration, bool) {
+	var failures int64
+	var lastFailure models.LoginAttempt
+
+	query := scope.Model(&models.LoginAttempt{}).Where("success = ? AND created_at > ?", false, since)
+	if err := query.Session(&gorm.Session{}).Count(&failures).Error; err != nil || failures == 0 {
+		return 0, false
+	}
+	if err := query.Session(&gorm.Session{}).Order("created_at desc").First(&lastFailure).Error; err != nil {
+		return 0, false
+	}
+
+	var until time.Time
+	locked := false
+	switch {
+	case int(failures) >= maxAttempts:
+		until = lastFailure.CreatedAt.Add(t.LockoutDuration)
+		locked = true
+	case int(failures) >= freeAttempts:
+		delay := t.MaxDelay
+		if shift := int(failures) - freeAttempts; shift < 20 {
+			if d := t.BaseDelay << uint(shift); d < t.MaxDelay {
+				delay = d
+			}
+		}
+		until = lastFailure.CreatedAt.Add(delay)
+	default:
+		return 0, false
+	}
+
+	remaining := time.Until(until)
+	if remaining <= 0 {
+		return 0, false
+	}
+	return remaining, locked
+}
+
+// Fail marks the attempt as failed for auditing and throttling
+func (r *LoginAttemptReservation) Fail(userID *uint, reason string) {
+	r.attempt.UserID = userID
+	r.attempt.Reason = reason
+	r.save()
+}
+
+// Succeed marks the attempt as successful, which resets the account's failure count
+func (r *LoginAttemptReservation) Succeed(userID uint) {
+	r.attempt.UserID = &userID
+	r.attempt.Success = true
+	r.attempt.Reason = ""
+	r.save()
+}
+
+// Cancel drops the attempt when its outcome is neither a failure nor a login,
+// e.g. the right password for an account that still needs its second factor
+func (r *LoginAttemptReservation) Cancel() {
+	if r.attempt.ID != 0 {
+		r.throttle.DB.Delete(&models.LoginAttempt{}, r.attempt.ID)
+	}
+}
+
+func (r *LoginAttemptReservation) save() {
+	if r.attempt.ID == 0 {
+		r.throttle.DB.Create(&r.attempt)
+		return
+	}
+	r.throttle.DB.Model(&models.LoginAttempt{}).Where("id = ?", r.attempt.ID).Updates(map[string]interface{}{
+		"user_id": r.attempt.UserID,
+		"success": r.attempt.Success,
+		"reason":  r.attempt.Reason,
+	})
+}
+
+func normalizeEmail(email string) string {
+	return truncate(strings.ToLower(strings.TrimSpace(email)), 100)
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
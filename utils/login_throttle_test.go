@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"meetup_backend/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestThrottle(t *testing.T) *LoginThrottle {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&models.LoginAttempt{}); err != nil {
+		t.Fatal(err)
+	}
+	return &LoginThrottle{
+		DB:                 db,
+		Window:             15 * time.Minute,
+		FreeAttempts:       3,
+		AccountMaxAttempts: 10,
+		IPFreeAttempts:     20,
+		IPMaxAttempts:      50,
+		BaseDelay:          time.Minute,
+		MaxDelay:           time.Hour,
+		LockoutDuration:    time.Hour,
+	}
+}
+
+func TestReserveCountsAttemptsStillInFlight(t *testing.T) {
+	throttle := newTestThrottle(t)
+
+	// Parallel guesses that have all been reserved before any password was checked
+	var allowed []*LoginAttemptReservation
+	for i := 0; i < 10; i++ {
+		attempt, wait, _ := throttle.Reserve("victim@example.com", "203.0.113.1", "test")
+		if wait == 0 {
+			allowed = append(allowed, attempt)
+		}
+	}
+	if len(allowed) != 3 {
+		t.Fatalf("%d attempts allowed, want the 3 free attempts", len(allowed))
+	}
+
+	for _, attempt := range allowed {
+		attempt.Fail(nil, "bad_password")
+	}
+	var failures int64
+	throttle.DB.Model(&models.LoginAttempt{}).Count(&failures)
+	if failures != 3 {
+		t.Fatalf("%d attempts recorded, want only the 3 that were checked", failures)
+	}
+}
+
+func TestSucceededReservationResetsAccount(t *testing.T) {
+	throttle := newTestThrottle(t)
+
+	for i := 0; i < 2; i++ {
+		attempt, _, _ := throttle.Reserve("alice@example.com", "203.0.113.1", "test")
+		attempt.Fail(nil, "bad_password")
+	}
+	attempt, _, _ := throttle.Reserve("alice@example.com", "203.0.113.1", "test")
+	attempt.Succeed(1)
+
+	for i := 0; i < 3; i++ {
+		attempt, wait, _ := throttle.Reserve("alice@example.com", "203.0.113.1", "test")
+		if wait > 0 {
+			t.Fatalf("attempt %d after a successful login was throttled for %v", i+1, wait)
+		}
+		attempt.Fail(nil, "bad_password")
+	}
+}
@@ -2,12 +2,21 @@ package utils
 
 import "golang.org/x/crypto/bcrypt"
 
+// PasswordCost is the bcrypt cost used for new hashes. Set from config at startup.
+var PasswordCost = 12
+
 func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 5)
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), PasswordCost)
 	return string(bytes), err
 }
 
 func CheckPasswordHash(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
-}
\ No newline at end of file
+}
+
+// NeedsRehash reports whether the hash was created with a weaker cost than PasswordCost
+func NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost < PasswordCost
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// Roles stored in User.Role
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Permission is a named capability checked by RequirePermission and Can
+type Permission string
+
+const (
+	PermManageAnyProduct Permission = "products:manage_any" // Update/delete products of other sellers
+	PermModerateChat     Permission = "chats:moderate"      // Read/delete chat rooms the user is not part of
+	PermManageUsers      Permission = "users:manage"        // Admin user management
+	PermModerateReports  Permission = "reports:moderate"    // Work the report queue, hide products, warn and suspend users
+)
+
+// rolePermissions maps each role to the permissions it grants
+var rolePermissions = map[string][]Permission{
+	RoleUser: {},
+	RoleModerator: {
+		PermManageAnyProduct,
+		PermModerateChat,
+		PermModerateReports,
+	},
+	RoleAdmin: {
+		PermManageAnyProduct,
+		PermModerateChat,
+		PermManageUsers,
+		PermModerateReports,
+	},
+}
+
+// IsValidRole reports whether role is one of the known roles
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission reports whether the role grants the permission
+func HasPermission(role string, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Can checks the permission against the role AuthMiddleware loaded for this request.
+// API keys only act within their scopes, so requests made with one never get
+// role permissions, whatever the owner's role is.
+func Can(c *fiber.Ctx, perm Permission) bool {
+	if c.Locals("api_key_id") != nil {
+		return false
+	}
+	role, _ := c.Locals("role").(string)
+	return HasPermission(role, perm)
+}
+
+// RequirePermission rejects requests whose user lacks the permission. Must run after AuthMiddleware.
+func RequirePermission(perm Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !Can(c, perm) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You do not have permission to perform this action"})
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"meetup_backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RevokeSession marks a session as revoked so its access and refresh tokens stop working
+func RevokeSession(db *gorm.DB, sessionID uint, reason string) error {
+	return db.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Updates(map[string]interface{}{
+			"revoked_at":     time.Now(),
+			"revoked_reason": reason,
+		}).Error
+}
+
+// RevokeUserSessions revokes every active session of a user except exceptSessionID (0 revokes all)
+func RevokeUserSessions(db *gorm.DB, userID uint, exceptSessionID uint, reason string) error {
+	return db.Model(&models.Session{}).
+		Where("user_id = ? AND id != ? AND revoked_at IS NULL", userID, exceptSessionID).
+		Updates(map[string]interface{}{
+			"revoked_at":     time.Now(),
+			"revoked_reason": reason,
+		}).Error
+}
+
+// CheckSession makes sure the session exists, belongs to the user and is still active
+func CheckSession(db *gorm.DB, sessionID uint, userID uint) error {
+	var session models.Session
+	if err := db.Select("id, user_id, expires_at, revoked_at").First(&session, sessionID).Error; err != nil {
+		return ErrSessionNotFound
+	}
+	if session.UserID != userID || !session.IsActive() {
+		return ErrSessionRevoked
+	}
+	return nil
+}
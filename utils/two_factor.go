@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"meetup_backend/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const recoveryCodeCount = 10
+
+// VerifyUserTOTP validates a code against the user's TOTP secret and records the
+// matched step, so the same code cannot be used twice.
+func VerifyUserTOTP(db *gorm.DB, user *models.User, code string) bool {
+	if user.TOTPSecret == "" {
+		return false
+	}
+
+	step, ok := ValidateTOTP(user.TOTPSecret, code, time.Now())
+	if !ok || step <= user.TOTPLastStep {
+		return false
+	}
+
+	// Conditional update guards against two requests racing with the same code
+	result := db.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", user.ID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false
+	}
+
+	user.TOTPLastStep = step
+	return true
+}
+
+// GenerateRecoveryCodes replaces the user's recovery codes with a fresh set and
+// returns the plain codes. They are shown to the user only once.
+func GenerateRecoveryCodes(db *gorm.DB, userID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := GenerateRandomToken(5)
+		if err != nil {
+			return nil, err
+		}
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		records = append(records, models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: HashToken(code),
+		})
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&records).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// ConsumeRecoveryCode marks a matching unused recovery code as used
+func ConsumeRecoveryCode(db *gorm.DB, userID uint, code string) bool {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if code == "" {
+		return false
+	}
+
+	result := db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, HashToken(code)).
+		Update("used_at", time.Now())
+	return result.Error == nil && result.RowsAffected > 0
+}
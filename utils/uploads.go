@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"errors"
+	"meetup_backend/models"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// RemoveUploadedFile deletes a file stored under ./uploads given its public URL
+// (e.g. /uploads/avatars/123.png). URLs that do not point into ./uploads are ignored.
+func RemoveUploadedFile(url string) error {
+	if !strings.HasPrefix(url, "/uploads/") {
+		return nil
+	}
+	rel := filepath.Clean(strings.TrimPrefix(url, "/"))
+	if !strings.HasPrefix(rel, "uploads"+string(filepath.Separator)) {
+		return nil
+	}
+	if err := os.Remove(rel); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// OwnsUpload reports whether url is a file the user uploaded. The stored URLs are
+// the generated ones, so paths like /uploads/avatars/../products/x.png never match.
+func OwnsUpload(db *gorm.DB, userID uint, url string) bool {
+	var count int64
+	db.Model(&models.Upload{}).Where("user_id = ? AND url = ?", userID, url).Count(&count)
+	return count > 0
+}
+
+// RemoveUserUpload deletes an uploaded file, but only if userID uploaded it
+func RemoveUserUpload(db *gorm.DB, userID uint, url string) error {
+	var upload models.Upload
+	err := db.Where("user_id = ? AND url = ?", userID, url).First(&upload).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := RemoveUploadedFile(upload.URL); err != nil {
+		return err
+	}
+	return db.Delete(&upload).Error
+}
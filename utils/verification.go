@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"meetup_backend/models"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// RequireVerifiedEmail blocks users who have not verified their email yet.
+// When enforce is false (policy disabled) every request passes through.
+// Must run after AuthMiddleware.
+func RequireVerifiedEmail(db *gorm.DB, enforce bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !enforce {
+			return c.Next()
+		}
+
+		userID, _ := c.Locals("user_id").(uint)
+
+		var user models.User
+		if err := db.Select("id, is_verified").First(&user, userID).Error; err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+		}
+
+		if !user.IsVerified {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Please verify your email address first",
+				"code":  "email_not_verified",
+			})
+		}
+
+		return c.Next()
+	}
+}